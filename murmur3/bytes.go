@@ -0,0 +1,109 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package murmur3
+
+import (
+	"hash"
+)
+
+// HashBytes computes MurmurHash3_x86_32 of data with the given seed.
+func HashBytes(data []byte, seed uint32) uint32 {
+	return hashBytes32(data, seed)
+}
+
+func hashBytes32[T string | []byte](data T, seed uint32) uint32 {
+	hash := seed
+	nblocks := len(data) / 4
+
+	for i := 0; i < nblocks; i++ {
+		b := data[4*i : 4*i+4]
+		key := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+		hash = MixHash(hash, MixKey(key))
+	}
+
+	return Finalize(mixTail32(hash, data[4*nblocks:]), int32(len(data)))
+}
+
+// mixTail32 mixes the last 0 to 3 bytes into the hash.
+func mixTail32[T string | []byte](hash uint32, tail T) uint32 {
+	var key uint32
+	switch len(tail) {
+	case 3:
+		key ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		key ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		key ^= uint32(tail[0])
+		hash ^= MixKey(key)
+	}
+	return hash
+}
+
+// digest32 is a streaming MurmurHash3_x86_32.
+type digest32 struct {
+	seed   uint32
+	hash   uint32
+	tail   [4]byte
+	ntail  int
+	length int
+}
+
+// New32 returns a hash.Hash32 computing MurmurHash3_x86_32 with seed 0.
+func New32() hash.Hash32 {
+	return New32WithSeed(0)
+}
+
+// New32WithSeed returns a hash.Hash32 computing MurmurHash3_x86_32 with the given seed.
+// The sum is written big-endian, as for hash/crc32 and hash/fnv.
+func New32WithSeed(seed uint32) hash.Hash32 {
+	return &digest32{seed: seed, hash: seed}
+}
+
+func (d *digest32) Size() int      { return 4 }
+func (d *digest32) BlockSize() int { return 4 }
+
+func (d *digest32) Reset() {
+	*d = digest32{seed: d.seed, hash: d.seed}
+}
+
+func (d *digest32) Write(p []byte) (int, error) {
+	n := len(p)
+	d.length += n
+
+	if d.ntail > 0 {
+		c := copy(d.tail[d.ntail:], p)
+		d.ntail += c
+		p = p[c:]
+		if d.ntail < 4 {
+			return n, nil
+		}
+		d.mixBlock(d.tail[:])
+		d.ntail = 0
+	}
+
+	for len(p) >= 4 {
+		d.mixBlock(p[:4])
+		p = p[4:]
+	}
+
+	d.ntail = copy(d.tail[:], p)
+	return n, nil
+}
+
+func (d *digest32) mixBlock(b []byte) {
+	key := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+	d.hash = MixHash(d.hash, MixKey(key))
+}
+
+func (d *digest32) Sum32() uint32 {
+	return Finalize(mixTail32(d.hash, d.tail[:d.ntail]), int32(d.length))
+}
+
+func (d *digest32) Sum(b []byte) []byte {
+	h := d.Sum32()
+	return append(b, byte(h>>24), byte(h>>16), byte(h>>8), byte(h))
+}
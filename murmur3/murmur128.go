@@ -0,0 +1,87 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package murmur3
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const c1_128 uint64 = 0x87c37b91114253d5
+const c2_128 uint64 = 0x4cf5ad432745937f
+
+// Sum128 computes MurmurHash3_x64_128 of data with seed 0.
+// The two halves are returned in the order of the reference implementation's output.
+func Sum128(data []byte) (h1 uint64, h2 uint64) {
+	return Sum128WithSeed(data, 0)
+}
+
+// Sum128WithSeed computes MurmurHash3_x64_128 of data with the given seed.
+func Sum128WithSeed(data []byte, seed uint32) (h1 uint64, h2 uint64) {
+	h1, h2 = uint64(seed), uint64(seed)
+	nblocks := len(data) / 16
+
+	for i := 0; i < nblocks; i++ {
+		k1 := binary.LittleEndian.Uint64(data[16*i:])
+		k2 := binary.LittleEndian.Uint64(data[16*i+8:])
+
+		h1 ^= mixK1_128(k1)
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		h2 ^= mixK2_128(k2)
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	tail := data[16*nblocks:]
+	var k1, k2 uint64
+	for i := len(tail) - 1; i >= 8; i-- {
+		k2 ^= uint64(tail[i]) << (8 * uint(i-8))
+	}
+	if len(tail) > 8 {
+		h2 ^= mixK2_128(k2)
+	}
+	for i := min(len(tail), 8) - 1; i >= 0; i-- {
+		k1 ^= uint64(tail[i]) << (8 * uint(i))
+	}
+	if len(tail) > 0 {
+		h1 ^= mixK1_128(k1)
+	}
+
+	h1 ^= uint64(len(data))
+	h2 ^= uint64(len(data))
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+func mixK1_128(k uint64) uint64 {
+	k *= c1_128
+	k = bits.RotateLeft64(k, 31)
+	return k * c2_128
+}
+
+func mixK2_128(k uint64) uint64 {
+	k *= c2_128
+	k = bits.RotateLeft64(k, 33)
+	return k * c1_128
+}
+
+// fmix64 forces all bits of a 64-bit hash block to avalanche
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -12,6 +12,10 @@
 // The implementations of HashUnordered and HashOrdered taken from ClojureJVM.
 package murmur3
 
+import (
+	"unicode/utf16"
+)
+
 const seed uint32 = 0
 const c1 uint32 = 0xcc9e2d51
 const c2 uint32 = 0x1b873593
@@ -61,36 +65,44 @@ func HashUInt64(input uint64) uint32 {
 	return Finalize(hash, 8)
 }
 
-// HashString computes a hash value for a string
+// HashString computes a hash value for the UTF-8 bytes of a string:  MurmurHash3_x86_32 with seed 0.
+// (This is not how Clojure hashes strings;  see sequtil.HashString.)
 func HashString(input string) uint32 {
+	return hashBytes32(input, seed)
+}
 
+// HashUnencodedChars computes a hash value for the UTF-16 code units of a string,
+// as Clojure's Murmur3.hashUnencodedChars does for a Java string.
+// (Clojure uses it for symbol and keyword names.)
+func HashUnencodedChars(input string) uint32 {
+	chars := utf16.Encode([]rune(input))
 	hash := seed
-	len := len(input)
 
-	// step through the string 4 bytes at a time
-	for i := 3; i < len; i += 4 {
-		key := uint32(input[i-3]) | uint32(input[i-2]<<8) | uint32(input[i-1]<<16) | uint32(input[i]<<24)
+	// step through the chars 2 at a time
+	for i := 1; i < len(chars); i += 2 {
+		key := uint32(chars[i-1]) | uint32(chars[i])<<16
 		key = MixKey(key)
 		hash = MixHash(hash, key)
 	}
 
-	// deal with remaining characters
-
-	if len != 0 {
-		var key uint32
-		switch len % 4 {
-		case 1:
-			key = uint32(input[len-1])
-		case 2:
-			key = uint32(input[len-2]) | uint32(input[len-1]<<8)
-		case 3:
-			key = uint32(input[len-3]) | uint32(input[len-2]<<8) | uint32(input[len-1]<<16)
-		}
+	// deal with a remaining char
+	if len(chars)&1 == 1 {
+		key := uint32(chars[len(chars)-1])
 		key = MixKey(key)
-		hash = MixHash(hash, key)
+		hash ^= key
 	}
 
-	return Finalize(hash, int32(len))
+	return Finalize(hash, int32(2*len(chars)))
+}
+
+// StringHashCode computes the Java String.hashCode of a string (over its UTF-16 code units).
+// Clojure hashes a string by applying HashInt32 to this value.
+func StringHashCode(input string) int32 {
+	var h int32
+	for _, c := range utf16.Encode([]rune(input)) {
+		h = 31*h + int32(c)
+	}
+	return h
 }
 
 // MixKey scrambles the bits in 32-bit value
@@ -0,0 +1,122 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package murmur3
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// smhasherVerification computes the SMHasher VerificationTest value for a hash:
+// hash the keys {}, {0}, {0, 1}, ..., {0, ..., 254} with seeds 256, 255, ..., 2,
+// hash the concatenated (little-endian) results with seed 0,
+// and return the first four bytes of that as a little-endian uint32.
+func smhasherVerification(hashSize int, h func(data []byte, seed uint32) []byte) uint32 {
+	key := make([]byte, 256)
+	hashes := make([]byte, 0, hashSize*256)
+	for i := 0; i < 256; i++ {
+		key[i] = byte(i)
+		hashes = append(hashes, h(key[:i], uint32(256-i))...)
+	}
+	return binary.LittleEndian.Uint32(h(hashes, 0))
+}
+
+func TestSMHasherVerification(t *testing.T) {
+	x86 := func(data []byte, seed uint32) []byte {
+		return binary.LittleEndian.AppendUint32(nil, HashBytes(data, seed))
+	}
+	if v := smhasherVerification(4, x86); v != 0xB0F57EE3 {
+		t.Errorf("MurmurHash3_x86_32 verification => %#X, want 0xB0F57EE3", v)
+	}
+
+	stream := func(data []byte, seed uint32) []byte {
+		d := New32WithSeed(seed)
+		for i := 0; i < len(data); i += 3 {
+			d.Write(data[i:min(i+3, len(data))])
+		}
+		return binary.LittleEndian.AppendUint32(nil, d.Sum32())
+	}
+	if v := smhasherVerification(4, stream); v != 0xB0F57EE3 {
+		t.Errorf("streaming MurmurHash3_x86_32 verification => %#X, want 0xB0F57EE3", v)
+	}
+
+	x64 := func(data []byte, seed uint32) []byte {
+		h1, h2 := Sum128WithSeed(data, seed)
+		return binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, h1), h2)
+	}
+	if v := smhasherVerification(16, x64); v != 0x6384BA69 {
+		t.Errorf("MurmurHash3_x64_128 verification => %#X, want 0x6384BA69", v)
+	}
+}
+
+var hashBytesTests = []struct {
+	in   string
+	seed uint32
+	out  uint32
+}{
+	{"", 0, 0},
+	{"", 1, 0x514E28B7},
+	{"", 0xffffffff, 0x81F16F39},
+	{"\xff\xff\xff\xff", 0, 0x76293B50},
+	{"\x21\x43\x65\x87", 0, 0xF55B516B},
+	{"\x21\x43\x65", 0, 0x7E4A8634},
+	{"\x21\x43", 0, 0xA0F7B07A},
+	{"\x21", 0, 0x72661CF4},
+	{"Hello, world!", 1234, 0xFAF6CDB3},
+	{"The quick brown fox jumps over the lazy dog", 0, 0x2E4FF723},
+}
+
+func TestHashBytes(t *testing.T) {
+	for i, tt := range hashBytesTests {
+		if h := HashBytes([]byte(tt.in), tt.seed); h != tt.out {
+			t.Errorf("%d. HashBytes(%q, %d) => %#x, want %#x", i, tt.in, tt.seed, h, tt.out)
+		}
+		if tt.seed == 0 {
+			if h := HashString(tt.in); h != tt.out {
+				t.Errorf("%d. HashString(%q) => %#x, want %#x", i, tt.in, h, tt.out)
+			}
+		}
+	}
+}
+
+func TestNew32(t *testing.T) {
+	d := New32()
+	for i, tt := range hashBytesTests {
+		if tt.seed != 0 {
+			continue
+		}
+		d.Reset()
+		for j := 0; j < len(tt.in); j++ {
+			d.Write([]byte{tt.in[j]})
+		}
+		if h := d.Sum32(); h != tt.out {
+			t.Errorf("%d. New32 bytewise %q => %#x, want %#x", i, tt.in, h, tt.out)
+		}
+		sum := d.Sum([]byte{0xAA})
+		if len(sum) != 5 || binary.BigEndian.Uint32(sum[1:]) != tt.out {
+			t.Errorf("%d. Sum => %x, want aa%08x", i, sum, tt.out)
+		}
+	}
+	if d.Size() != 4 || d.BlockSize() != 4 {
+		t.Errorf("Size, BlockSize => %d, %d, want 4, 4", d.Size(), d.BlockSize())
+	}
+}
+
+func TestSum128(t *testing.T) {
+	tests := []struct {
+		in     string
+		seed   uint32
+		h1, h2 uint64
+	}{
+		{"", 0, 0, 0},
+		{"hello", 0, 0xcbd8a7b341bd9b02, 0x5b1e906a48ae1d19},
+		{"The quick brown fox jumps over the lazy dog", 0, 0xe34bbc7bbc071b6c, 0x7a433ca9c49a9347},
+	}
+	for i, tt := range tests {
+		if h1, h2 := Sum128WithSeed([]byte(tt.in), tt.seed); h1 != tt.h1 || h2 != tt.h2 {
+			t.Errorf("%d. Sum128(%q) => %#x %#x, want %#x %#x", i, tt.in, h1, h2, tt.h1, tt.h2)
+		}
+	}
+}
@@ -85,7 +85,7 @@ func (c *chunkedSeq) Empty() iseq.PCollection {
 }
 
 func (c *chunkedSeq) Equiv(o interface{}) bool {
-	return sequtil.Equiv(c, o)
+	return sequtil.SequentialEquiv(c, o)
 }
 
 func (c *chunkedSeq) First() interface{} {
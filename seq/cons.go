@@ -111,12 +111,7 @@ func (c *Cons) Equiv(o interface{}) bool {
 		return true
 	}
 
-	if os, ok := o.(iseq.Seqable); ok {
-		return sequtil.SeqEquiv(c, os.Seq())
-	}
-
-	// TODO: handle built-in 'sequable' things such as arrays, slices, strings
-	return false
+	return sequtil.SequentialEquiv(c, o)
 }
 
 // Hash computes a Hash value for the Cons, treated as a sequence.
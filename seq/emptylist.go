@@ -6,6 +6,8 @@ package seq
 
 import (
 	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/murmur3"
+	"github.com/dmiller/go-seq/sequtil"
 )
 
 // EmptyList implements an empty iseq.PList
@@ -116,11 +118,15 @@ func (e *EmptyList) Equiv(o interface{}) bool {
 		return s.Seq() == nil
 	}
 
+	if sequtil.IsGoSequential(o) {
+		return sequtil.SeqSliceEquiv(nil, o)
+	}
+
 	return false
 }
 
-// TODO: figure out a standard hash code for empty sequences?
-var hashCode uint32 = 1337
+// The hash of any empty ordered collection, as in Clojure
+var hashCode = murmur3.FinalizeCollHash(1, 0)
 
 // Hash computes a hash code for an EmptyList (all the same)
 func (e *EmptyList) Hash() uint32 {
@@ -0,0 +1,448 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+)
+
+// Seqs over native Go values.
+//
+// Each constructor returns nil for an empty source, as an iseq.Seq must have at least one element,
+// except NewChanSeq, which can't tell whether a channel is empty without receiving from it.
+// The seqs registered with sequtil.RegisterSeqConverter make ConvertToSeq (and so MapCons, smartCons, ...)
+// accept Go slices, arrays, maps, strings (including named string types), and channels that can receive.
+
+func init() {
+	sequtil.RegisterSeqConverter(convertGoValue)
+}
+
+func convertGoValue(o interface{}) (iseq.Seq, bool) {
+	switch o := o.(type) {
+	case []interface{}:
+		return NewArraySeq(o), true
+	case string:
+		return NewStringSeq(o), true
+	}
+
+	switch v := reflect.ValueOf(o); v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return NewSliceSeq(o), true
+	case reflect.Map:
+		return NewMapSeq(o), true
+	case reflect.String:
+		return NewStringSeq(v.String()), true
+	case reflect.Chan:
+		if _, ok := recvChan(o); ok {
+			return newChanSeq(v).Seq(), true
+		}
+	}
+	return nil, false
+}
+
+// ArraySeq is an iseq.Seq over a []interface{}.
+// The slice is not copied;  it should not be modified while the seq is in use.
+type ArraySeq struct {
+	AMeta
+	array []interface{}
+	i     int
+}
+
+// NewArraySeq returns a seq over the elements of a.
+func NewArraySeq(a []interface{}) iseq.Seq {
+	return newArraySeq(a, 0)
+}
+
+func newArraySeq(a []interface{}, i int) iseq.Seq {
+	if i >= len(a) {
+		return nil
+	}
+	return &ArraySeq{array: a, i: i}
+}
+
+func (a *ArraySeq) WithMeta(meta iseq.PMap) iseq.MetaW {
+	return &ArraySeq{AMeta: AMeta{meta}, array: a.array, i: a.i}
+}
+
+func (a *ArraySeq) First() interface{} {
+	return a.array[a.i]
+}
+
+func (a *ArraySeq) Next() iseq.Seq {
+	return newArraySeq(a.array, a.i+1)
+}
+
+func (a *ArraySeq) More() iseq.Seq {
+	return moreFromSeq(a)
+}
+
+func (a *ArraySeq) Cons(o interface{}) iseq.PCollection {
+	return a.ConsS(o)
+}
+
+func (a *ArraySeq) ConsS(o interface{}) iseq.Seq {
+	return NewCons(o, a)
+}
+
+func (a *ArraySeq) Count() int {
+	return a.Count1()
+}
+
+func (a *ArraySeq) Count1() int {
+	return len(a.array) - a.i
+}
+
+func (a *ArraySeq) Empty() iseq.PCollection {
+	return CachedEmptyList
+}
+
+func (a *ArraySeq) Equiv(o interface{}) bool {
+	return sequtil.SequentialEquiv(a, o)
+}
+
+func (a *ArraySeq) Hash() uint32 {
+	return sequtil.HashSeq(a)
+}
+
+func (a *ArraySeq) Seq() iseq.Seq {
+	return a
+}
+
+// sliceSeq is an iseq.Seq over any Go slice or array, via reflection.
+type sliceSeq struct {
+	AMeta
+	v reflect.Value
+	i int
+}
+
+// NewSliceSeq returns a seq over the elements of a Go slice or array.
+// Panics with a *sequtil.TypeError if o is not a slice or array.
+func NewSliceSeq(o interface{}) iseq.Seq {
+	if a, ok := o.([]interface{}); ok {
+		return NewArraySeq(a)
+	}
+	v := reflect.ValueOf(o)
+	if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+		panic(&sequtil.TypeError{Op: "NewSliceSeq", Value: o, Want: "a slice or array"})
+	}
+	return newSliceSeq(v, 0)
+}
+
+func newSliceSeq(v reflect.Value, i int) iseq.Seq {
+	if i >= v.Len() {
+		return nil
+	}
+	return &sliceSeq{v: v, i: i}
+}
+
+func (s *sliceSeq) WithMeta(meta iseq.PMap) iseq.MetaW {
+	return &sliceSeq{AMeta: AMeta{meta}, v: s.v, i: s.i}
+}
+
+func (s *sliceSeq) First() interface{} {
+	return s.v.Index(s.i).Interface()
+}
+
+func (s *sliceSeq) Next() iseq.Seq {
+	return newSliceSeq(s.v, s.i+1)
+}
+
+func (s *sliceSeq) More() iseq.Seq {
+	return moreFromSeq(s)
+}
+
+func (s *sliceSeq) Cons(o interface{}) iseq.PCollection {
+	return s.ConsS(o)
+}
+
+func (s *sliceSeq) ConsS(o interface{}) iseq.Seq {
+	return NewCons(o, s)
+}
+
+func (s *sliceSeq) Count() int {
+	return s.Count1()
+}
+
+func (s *sliceSeq) Count1() int {
+	return s.v.Len() - s.i
+}
+
+func (s *sliceSeq) Empty() iseq.PCollection {
+	return CachedEmptyList
+}
+
+func (s *sliceSeq) Equiv(o interface{}) bool {
+	return sequtil.SequentialEquiv(s, o)
+}
+
+func (s *sliceSeq) Hash() uint32 {
+	return sequtil.HashSeq(s)
+}
+
+func (s *sliceSeq) Seq() iseq.Seq {
+	return s
+}
+
+// StringSeq is an iseq.Seq over the runes of a string.
+type StringSeq struct {
+	AMeta
+	s string
+	i int // byte offset of the current rune
+}
+
+// NewStringSeq returns a seq over the runes of s.
+func NewStringSeq(s string) iseq.Seq {
+	return newStringSeq(s, 0)
+}
+
+func newStringSeq(s string, i int) iseq.Seq {
+	if i >= len(s) {
+		return nil
+	}
+	return &StringSeq{s: s, i: i}
+}
+
+func (s *StringSeq) WithMeta(meta iseq.PMap) iseq.MetaW {
+	return &StringSeq{AMeta: AMeta{meta}, s: s.s, i: s.i}
+}
+
+// First returns the current rune (as a rune).
+func (s *StringSeq) First() interface{} {
+	r, _ := utf8.DecodeRuneInString(s.s[s.i:])
+	return r
+}
+
+func (s *StringSeq) Next() iseq.Seq {
+	_, size := utf8.DecodeRuneInString(s.s[s.i:])
+	return newStringSeq(s.s, s.i+size)
+}
+
+func (s *StringSeq) More() iseq.Seq {
+	return moreFromSeq(s)
+}
+
+func (s *StringSeq) Cons(o interface{}) iseq.PCollection {
+	return s.ConsS(o)
+}
+
+func (s *StringSeq) ConsS(o interface{}) iseq.Seq {
+	return NewCons(o, s)
+}
+
+// Count returns the number of runes remaining.
+func (s *StringSeq) Count() int {
+	return utf8.RuneCountInString(s.s[s.i:])
+}
+
+func (s *StringSeq) Empty() iseq.PCollection {
+	return CachedEmptyList
+}
+
+func (s *StringSeq) Equiv(o interface{}) bool {
+	return sequtil.SequentialEquiv(s, o)
+}
+
+func (s *StringSeq) Hash() uint32 {
+	return sequtil.HashSeq(s)
+}
+
+func (s *StringSeq) Seq() iseq.Seq {
+	return s
+}
+
+// MapSeq is an iseq.Seq of MapEntry values over a Go map.
+// The entries are copied when the seq is created, in Go's (random) map iteration order.
+type MapSeq struct {
+	AMeta
+	entries []MapEntry
+	i       int
+}
+
+// NewMapSeq returns a seq of the entries of a Go map.
+// Panics with a *sequtil.TypeError if m is not a map.
+func NewMapSeq(m interface{}) iseq.Seq {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic(&sequtil.TypeError{Op: "NewMapSeq", Value: m, Want: "a map"})
+	}
+	entries := make([]MapEntry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		entries = append(entries, MapEntry{iter.Key().Interface(), iter.Value().Interface()})
+	}
+	return newMapSeq(entries, 0)
+}
+
+func newMapSeq(entries []MapEntry, i int) iseq.Seq {
+	if i >= len(entries) {
+		return nil
+	}
+	return &MapSeq{entries: entries, i: i}
+}
+
+func (m *MapSeq) WithMeta(meta iseq.PMap) iseq.MetaW {
+	return &MapSeq{AMeta: AMeta{meta}, entries: m.entries, i: m.i}
+}
+
+func (m *MapSeq) First() interface{} {
+	return m.entries[m.i]
+}
+
+func (m *MapSeq) Next() iseq.Seq {
+	return newMapSeq(m.entries, m.i+1)
+}
+
+func (m *MapSeq) More() iseq.Seq {
+	return moreFromSeq(m)
+}
+
+func (m *MapSeq) Cons(o interface{}) iseq.PCollection {
+	return m.ConsS(o)
+}
+
+func (m *MapSeq) ConsS(o interface{}) iseq.Seq {
+	return NewCons(o, m)
+}
+
+func (m *MapSeq) Count() int {
+	return m.Count1()
+}
+
+func (m *MapSeq) Count1() int {
+	return len(m.entries) - m.i
+}
+
+func (m *MapSeq) Empty() iseq.PCollection {
+	return CachedEmptyList
+}
+
+func (m *MapSeq) Equiv(o interface{}) bool {
+	return sequtil.SequentialEquiv(m, o)
+}
+
+func (m *MapSeq) Hash() uint32 {
+	return sequtil.HashSeq(m)
+}
+
+func (m *MapSeq) Seq() iseq.Seq {
+	return m
+}
+
+// ChanSeq is a lazy iseq.Seq of the values received from a channel.
+// Nothing is received until the seq is used:  each value is received once, the first time
+// First, Next, or Seq needs it, and is then cached, so the seq can be traversed any number of times.
+// The seq ends when the channel is closed.
+//
+// Unlike other seqs, a ChanSeq from NewChanSeq can be empty:  if the channel is closed before
+// sending anything, First returns nil and Seq returns nil.  ConvertToSeq receives the first value,
+// so it returns nil for an empty channel, as for any other empty source.
+type ChanSeq struct {
+	AMeta
+	cell *chanCell
+}
+
+// chanCell holds a value received from a channel and the cell for the value after it.
+type chanCell struct {
+	ch   reflect.Value
+	once sync.Once
+	val  interface{}
+	next *chanCell // nil if the channel was closed instead
+}
+
+// recv receives the cell's value, if that has not been done yet.
+func (c *chanCell) recv() *chanCell {
+	c.once.Do(func() {
+		if x, ok := c.ch.Recv(); ok {
+			c.val, c.next = x.Interface(), &chanCell{ch: c.ch}
+		}
+	})
+	return c
+}
+
+// closed returns true if the channel was closed instead of sending the cell's value.
+func (c *chanCell) closed() bool {
+	return c.recv().next == nil
+}
+
+// NewChanSeq returns a seq of the values received from the channel ch.
+// It does not block:  the first value is received when the seq is first used.
+// Panics with a *sequtil.TypeError if ch is not a channel that can receive.
+func NewChanSeq(ch interface{}) iseq.Seq {
+	v, ok := recvChan(ch)
+	if !ok {
+		panic(&sequtil.TypeError{Op: "NewChanSeq", Value: ch, Want: "a receivable channel"})
+	}
+	return newChanSeq(v)
+}
+
+// recvChan returns the reflect.Value of ch, and true if it is a channel that can receive.
+func recvChan(ch interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(ch)
+	return v, v.Kind() == reflect.Chan && v.Type().ChanDir()&reflect.RecvDir != 0
+}
+
+func newChanSeq(ch reflect.Value) *ChanSeq {
+	return &ChanSeq{cell: &chanCell{ch: ch}}
+}
+
+func (c *ChanSeq) WithMeta(meta iseq.PMap) iseq.MetaW {
+	return &ChanSeq{AMeta: AMeta{meta}, cell: c.cell}
+}
+
+// First blocks until the first value is received.  Returns nil if the channel is closed first.
+func (c *ChanSeq) First() interface{} {
+	return c.cell.recv().val
+}
+
+// Next blocks until the next value is received or the channel is closed.
+func (c *ChanSeq) Next() iseq.Seq {
+	if c.cell.closed() || c.cell.next.closed() {
+		return nil
+	}
+	return &ChanSeq{cell: c.cell.next}
+}
+
+func (c *ChanSeq) More() iseq.Seq {
+	return moreFromSeq(c)
+}
+
+func (c *ChanSeq) Cons(o interface{}) iseq.PCollection {
+	return c.ConsS(o)
+}
+
+func (c *ChanSeq) ConsS(o interface{}) iseq.Seq {
+	return NewCons(o, c)
+}
+
+// Count receives the remaining values; it does not return until the channel is closed.
+func (c *ChanSeq) Count() int {
+	if c.cell.closed() {
+		return 0
+	}
+	return sequtil.SeqCount(c)
+}
+
+func (c *ChanSeq) Empty() iseq.PCollection {
+	return CachedEmptyList
+}
+
+func (c *ChanSeq) Equiv(o interface{}) bool {
+	return sequtil.SequentialEquiv(c.Seq(), o)
+}
+
+func (c *ChanSeq) Hash() uint32 {
+	return sequtil.HashSeq(c.Seq())
+}
+
+// Seq blocks until the first value is received.  Returns nil if the channel is closed first.
+func (c *ChanSeq) Seq() iseq.Seq {
+	if c.cell.closed() {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,144 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"errors"
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
+	"testing"
+)
+
+func seqToSlice(s iseq.Seq) []interface{} {
+	var ret []interface{}
+	for ; s != nil; s = s.Next() {
+		ret = append(ret, s.First())
+	}
+	return ret
+}
+
+type label string
+
+func TestConvertToSeqGoValues(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out *PVector
+		n   int
+	}{
+		{[]interface{}{1, "a", nil}, NewPVectorFromItems(1, "a", nil), 3},
+		{[]int{1, 2, 3}, NewPVectorFromItems(1, 2, 3), 3},
+		{[3]string{"a", "b", "c"}, NewPVectorFromItems("a", "b", "c"), 3},
+		{"héllo", NewPVectorFromItems('h', 'é', 'l', 'l', 'o'), 5},
+		{label("hé"), NewPVectorFromItems('h', 'é'), 2},
+		{map[string]int{"a": 1}, NewPVectorFromItems(MapEntry{"a", 1}), 1},
+	}
+	for i, tt := range tests {
+		s, err := sequtil.ConvertToSeqE(tt.in)
+		if err != nil {
+			t.Errorf("%d. ConvertToSeqE(%v) => error %v", i, tt.in, err)
+			continue
+		}
+		if !tt.out.Equiv(seqToSlice(s)) || !s.Equiv(tt.out) {
+			t.Errorf("%d. ConvertToSeq(%v) => %v, want %v", i, tt.in, seqToSlice(s), tt.out)
+		}
+		if s.Count() != tt.n {
+			t.Errorf("%d. Count => %d, want %d", i, s.Count(), tt.n)
+		}
+		if s.(iseq.Hashable).Hash() != tt.out.Hash() {
+			t.Errorf("%d. seq should hash as the vector", i)
+		}
+	}
+
+	for i, empty := range []interface{}{[]interface{}{}, []int(nil), [0]int{}, "", map[int]int{}} {
+		if s := sequtil.ConvertToSeq(empty); s != nil {
+			t.Errorf("%d. ConvertToSeq(%v) => %v, want nil", i, empty, s)
+		}
+	}
+}
+
+func TestCountGoValues(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out int
+	}{
+		{[]int{1, 2}, 2},
+		{[4]bool{}, 4},
+		{map[int]int{1: 1, 2: 2, 3: 3}, 3},
+		{"abc", 3},
+		{"héllo", 5},
+		{label("hé"), 2},
+	}
+	for i, tt := range tests {
+		if n := sequtil.Count(tt.in); n != tt.out {
+			t.Errorf("%d. Count(%v) => %d, want %d", i, tt.in, n, tt.out)
+		}
+	}
+	if _, err := sequtil.CountE(make(chan int)); err == nil {
+		t.Errorf("CountE(chan) should fail")
+	}
+}
+
+func TestChanSeq(t *testing.T) {
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= 3; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+
+	s := sequtil.ConvertToSeq(ch)
+	if s.First() != 1 {
+		t.Fatalf("First => %v, want 1", s.First())
+	}
+	// traversing twice sees the same values
+	for pass := 0; pass < 2; pass++ {
+		if !s.Equiv([]int{1, 2, 3}) {
+			t.Errorf("pass %d: ChanSeq => %v, want [1 2 3]", pass, seqToSlice(s))
+		}
+	}
+
+	closed := make(chan string)
+	close(closed)
+	if c := NewChanSeq(closed); c.First() != nil || c.Next() != nil || c.Seq() != nil || c.Count() != 0 {
+		t.Errorf("NewChanSeq on a closed channel should be empty")
+	}
+	if s := sequtil.ConvertToSeq(closed); s != nil {
+		t.Errorf("ConvertToSeq on a closed channel => %v, want nil", s)
+	}
+
+	// nothing is received until the seq is used
+	later := make(chan int)
+	lazy := NewChanSeq(later)
+	go func() { later <- 7 }()
+	if v := lazy.First(); v != 7 {
+		t.Errorf("First => %v, want 7", v)
+	}
+
+	var te *sequtil.TypeError
+	if _, err := sequtil.ConvertToSeqE(make(chan<- int)); !errors.As(err, &te) {
+		t.Errorf("ConvertToSeqE on a send-only channel => %v, want a TypeError", err)
+	}
+
+	defer func() {
+		if _, ok := recover().(*sequtil.TypeError); !ok {
+			t.Errorf("NewChanSeq on a send-only channel should panic with a TypeError")
+		}
+	}()
+	NewChanSeq(make(chan<- int))
+}
+
+func TestMapConsGoValues(t *testing.T) {
+	m := NewPHashMapFromItems("a", 1)
+	if r := m.Cons(map[string]int{"b": 2, "c": 3}).(iseq.PMap); !r.Equiv(map[string]int{"a": 1, "b": 2, "c": 3}) {
+		t.Errorf("Cons(Go map) => %v", r)
+	}
+	if r := m.Cons([]interface{}{MapEntry{"b", 2}}).(iseq.PMap); !r.Equiv(map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("Cons(slice of entries) => %v", r)
+	}
+	if r := NewCons(0, NewArraySeq([]interface{}{1, 2})); !r.Equiv([]int{0, 1, 2}) {
+		t.Errorf("NewCons onto an ArraySeq => %v", seqToSlice(r))
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
+	"testing"
+)
+
+// Values of (hash x) from Clojure 1.6+ on the JVM
+func TestCollectionHashMatchesClojure(t *testing.T) {
+	tests := []struct {
+		name string
+		in   iseq.Hashable
+		out  int32
+	}{
+		{"[]", EmptyPVector, -2017569654},
+		{"()", CachedEmptyList, -2017569654},
+		{"{}", EmptyPHashMap, -15128758},
+		{"#{}", EmptyPHashSet, -15128758},
+		{"[1 2 3]", NewPVectorFromItems(1, 2, 3), 736442005},
+		{"'(1 2 3)", NewPListFromSlice([]interface{}{1, 2, 3}), 736442005},
+		{"(cons 1 '(2 3))", NewCons(1, NewPListFromSlice([]interface{}{2, 3})), 736442005},
+		{"{1 2}", NewPHashMapFromItems(1, 2), 1952097988},
+		{"#{1 2 3}", NewPHashSetFromItems(1, 2, 3), 439094965},
+	}
+	for i, tt := range tests {
+		if h := int32(tt.in.Hash()); h != tt.out {
+			t.Errorf("%d. (hash %s) => %d, want %d", i, tt.name, h, tt.out)
+		}
+	}
+}
+
+func TestMapEntryHashesAsVector(t *testing.T) {
+	e := MapEntry{"a", 1}
+	if e.Hash() != NewPVectorFromItems("a", 1).Hash() {
+		t.Errorf("MapEntry hash %d differs from the hash of the vector [key val]", e.Hash())
+	}
+}
+
+func TestMixedIntegerKeys(t *testing.T) {
+	m := NewPHashMapFromItems(1, "one", int64(2), "two")
+	if m.ValAt(int8(1)) != "one" || m.ValAt(uint(2)) != "two" {
+		t.Errorf("integer keys of different types with equal values should find the same entry")
+	}
+	if m.ContainsKey(1.0) {
+		t.Errorf("a float key should not find an integer entry")
+	}
+}
+
+func TestGoValuesMatchCollections(t *testing.T) {
+	tests := []struct {
+		name string
+		coll iseq.PCollection
+		val  interface{}
+	}{
+		{"[1 2 3] slice", NewPVectorFromItems(1, 2, 3), []int{1, 2, 3}},
+		{"[1 2 3] array", NewPVectorFromItems(1, 2, 3), [3]int64{1, 2, 3}},
+		{"'(1 2 3) slice", NewPListFromSlice([]interface{}{1, 2, 3}), []interface{}{1, 2, 3}},
+		{"[] slice", EmptyPVector, []string{}},
+		{"() slice", CachedEmptyList, []string(nil)},
+		{"{1 2} map", NewPHashMapFromItems(1, 2), map[int]int{1: 2}},
+		{"{\"a\" [1]} map", NewPHashMapFromItems("a", NewPVectorFromItems(1)), map[string][]int{"a": {1}}},
+	}
+	for i, tt := range tests {
+		if !tt.coll.Equiv(tt.val) {
+			t.Errorf("%d. %s: collection.Equiv(%v) => false, want true", i, tt.name, tt.val)
+		}
+		if !sequtil.Equiv(tt.val, tt.coll) {
+			t.Errorf("%d. %s: Equiv(%v, collection) => false, want true", i, tt.name, tt.val)
+		}
+		if h1, h2 := sequtil.Hash(tt.coll), sequtil.Hash(tt.val); h1 != h2 {
+			t.Errorf("%d. %s: collection hash %d, Go value hash %d, want equal", i, tt.name, h1, h2)
+		}
+	}
+
+	if NewPVectorFromItems(1, 2).Equiv([]int{1, 2, 3}) || NewPHashMapFromItems(1, 2).Equiv(map[int]int{1: 3}) {
+		t.Errorf("collections should not equal Go values with different elements")
+	}
+}
+
+func TestSliceKeysInPHashMap(t *testing.T) {
+	m := NewPHashMapFromItems([]int{1, 2}, "a", map[string]int{"x": 1}, "b")
+	if v := m.ValAt([]int{1, 2}); v != "a" {
+		t.Errorf("ValAt([]int{1, 2}) => %v, want a", v)
+	}
+	if v := m.ValAt(NewPVectorFromItems(1, 2)); v != "a" {
+		t.Errorf("ValAt([1 2]) => %v, want a", v)
+	}
+	if v := m.ValAt(map[string]int{"x": 1}); v != "b" {
+		t.Errorf("ValAt(map) => %v, want b", v)
+	}
+	if m.ContainsKey([]int{2, 1}) {
+		t.Errorf("ContainsKey([]int{2, 1}) => true, want false")
+	}
+}
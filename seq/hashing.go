@@ -0,0 +1,286 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/murmur3"
+	"github.com/dmiller/go-seq/sequtil"
+	"math/bits"
+	"sync/atomic"
+)
+
+// A Hasher supplies the key semantics of a PHashMap or PHashSet:  how keys are hashed
+// and when two keys are the same key.  Keys that are Equiv must have the same Hash.
+//
+// The default, used when no Hasher is given, is sequtil.Hash and sequtil.Equiv.
+// A Hasher governs lookup within its map only.  Equiv and Hash on maps and sets as values
+// always use sequtil.Equiv and sequtil.Hash on the keys, whatever the strategies of the maps involved,
+// so that they are symmetric and consistent with each other.
+// (For example, a case-insensitive map holding "A" is not equivalent to one holding "a".)
+type Hasher interface {
+	Hash(key interface{}) uint32
+	Equiv(k1 interface{}, k2 interface{}) bool
+}
+
+// NewHasher returns a Hasher using the given functions.
+func NewHasher(hash func(key interface{}) uint32, equiv func(k1 interface{}, k2 interface{}) bool) Hasher {
+	return &funcHasher{hash, equiv}
+}
+
+type funcHasher struct {
+	hash  func(key interface{}) uint32
+	equiv func(k1 interface{}, k2 interface{}) bool
+}
+
+func (f *funcHasher) Hash(key interface{}) uint32               { return f.hash(key) }
+func (f *funcHasher) Equiv(k1 interface{}, k2 interface{}) bool { return f.equiv(k1, k2) }
+
+// NewPHashMapWithHasher returns an empty PHashMap using h for its keys.
+// Maps derived from it (by AssocM, Without, Empty, ...) use the same Hasher.
+func NewPHashMapWithHasher(h Hasher) *PHashMap {
+	return &PHashMap{hasher: h}
+}
+
+// standardHasher is the default Hasher
+type standardHasher struct{}
+
+func (standardHasher) Hash(key interface{}) uint32               { return sequtil.Hash(key) }
+func (standardHasher) Equiv(k1 interface{}, k2 interface{}) bool { return sequtil.Equiv(k1, k2) }
+
+// keys returns the Hasher for the map's keys
+func (m *PHashMap) keys() Hasher {
+	if m.hasher == nil {
+		return standardHasher{}
+	}
+	return m.hasher
+}
+
+// hasStandardKeys returns true if looking up a key in the map finds what it would under
+// sequtil.Hash and sequtil.Equiv, so that Equiv can look up the keys of another map in it.
+// A secure keyHasher does not qualify:  it hashes []byte keys unlike equivalent vectors.
+func (m *PHashMap) hasStandardKeys() bool {
+	switch h := m.hasher.(type) {
+	case nil, standardHasher:
+		return true
+	case *keyHasher:
+		return !h.secure
+	}
+	return false
+}
+
+// sameItems returns true if s1 and s2 hold the same items, matched one for one by equiv.
+// Items are indexed by sequtil.Hash of key(item), so equivalent items must have equivalent keys.
+// Repeats count:  a Hasher finer than sequtil.Equiv can hold several keys that sequtil.Equiv
+// considers the same, and a map holding two of them is not equivalent to one holding one.
+func sameItems(s1 iseq.Seq, s2 iseq.Seq, key func(interface{}) interface{}, equiv func(interface{}, interface{}) bool) bool {
+	pending := make(map[uint32][]interface{})
+	n := 0
+	for ; s2 != nil; s2 = s2.Next() {
+		x := s2.First()
+		h := itemHash(key(x))
+		pending[h] = append(pending[h], x)
+		n++
+	}
+	for ; s1 != nil; s1 = s1.Next() {
+		x := s1.First()
+		h := itemHash(key(x))
+		xs := pending[h]
+		i := 0
+		for i < len(xs) && !equiv(x, xs[i]) {
+			i++
+		}
+		if i == len(xs) {
+			return false
+		}
+		xs[i] = xs[len(xs)-1]
+		pending[h] = xs[:len(xs)-1]
+		n--
+	}
+	return n == 0
+}
+
+// itemHash is sequtil.Hash, with unhashable keys (allowed by a custom Hasher) all in one bucket.
+func itemHash(k interface{}) uint32 {
+	h, err := sequtil.HashE(k)
+	if err != nil {
+		return 0
+	}
+	return h
+}
+
+func entryKey(x interface{}) interface{} {
+	return x.(iseq.MapEntry).Key()
+}
+
+func entryEquiv(x interface{}, y interface{}) bool {
+	e1, e2 := x.(iseq.MapEntry), y.(iseq.MapEntry)
+	return sequtil.Equiv(e1.Key(), e2.Key()) && sequtil.Equiv(e1.Val(), e2.Val())
+}
+
+func itself(x interface{}) interface{} {
+	return x
+}
+
+// HashOptions controls how a PHashMap (or PHashSet) hashes its keys.
+//
+// By default keys are hashed with sequtil.Hash, which is unseeded and matches Clojure.
+// Anyone who can choose the keys of such a map can choose keys with colliding hashes
+// and degrade lookups to linear scans.
+// A non-zero Seed mixes the seed into every key hash, so the trie layout differs from map to map;
+// Secure additionally hashes string and []byte keys with SipHash-2-4 keyed by the seed, so colliding keys
+// cannot be computed without knowing the seed.  Other keys have the seed mixed into sequtil.Hash,
+// which changes the layout but not which keys collide.
+//
+// In a Secure map, a []byte key no longer hashes like the equivalent vector of integers
+// (a PVector, Go slice, or seq of the same numbers), so a lookup with one does not find an entry
+// stored under the other.  Use one representation for such keys in a Secure map.
+//
+// The options affect only where keys live in the trie.
+// Seq, Equiv, and Hash on the map are unchanged, so maps with different options holding the same entries
+// are equivalent and hash the same.
+type HashOptions struct {
+	Seed   uint64
+	Secure bool
+}
+
+// RandomHashOptions returns secure options with a seed from crypto/rand.
+func RandomHashOptions() HashOptions {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return HashOptions{Seed: binary.LittleEndian.Uint64(b[:]), Secure: true}
+}
+
+// NewPHashMapWithOptions returns an empty PHashMap hashing its keys as specified.
+// Maps derived from it (by AssocM, Without, Empty, ...) use the same options.
+func NewPHashMapWithOptions(opts HashOptions) *PHashMap {
+	return &PHashMap{hasher: asHasher(newKeyHasher(opts))}
+}
+
+// SetDefaultHashOptions sets the options used by the NewPHashMapFrom* and NewPHashSetFrom* factories.
+// Maps already created keep their options.  EmptyPHashMap and EmptyPHashSet always use the default hashing.
+func SetDefaultHashOptions(opts HashOptions) {
+	defaultHasher.Store(newKeyHasher(opts))
+}
+
+var defaultHasher atomic.Pointer[keyHasher]
+
+func defaultEmptyPHashMap() *PHashMap {
+	h := defaultHasher.Load()
+	if h == nil {
+		return EmptyPHashMap
+	}
+	return &PHashMap{hasher: h}
+}
+
+func asHasher(h *keyHasher) Hasher {
+	if h == nil {
+		return nil
+	}
+	return h
+}
+
+// keyHasher is the Hasher for HashOptions:  keys are compared by sequtil.Equiv.
+type keyHasher struct {
+	seed   uint32
+	secure bool
+	k0, k1 uint64
+}
+
+func newKeyHasher(opts HashOptions) *keyHasher {
+	if opts == (HashOptions{}) {
+		return nil
+	}
+	return &keyHasher{
+		seed:   uint32(opts.Seed) ^ uint32(opts.Seed>>32),
+		secure: opts.Secure,
+		k0:     opts.Seed,
+		k1:     splitmix64(opts.Seed),
+	}
+}
+
+func (h *keyHasher) Equiv(k1 interface{}, k2 interface{}) bool {
+	return sequtil.Equiv(k1, k2)
+}
+
+func (h *keyHasher) Hash(k interface{}) uint32 {
+	if h.secure {
+		switch k := k.(type) {
+		case string:
+			x := sipHash24(h.k0, h.k1, k)
+			return uint32(x) ^ uint32(x>>32)
+		case []byte:
+			x := sipHash24(h.k0, h.k1, k)
+			return uint32(x) ^ uint32(x>>32)
+		}
+	}
+	return murmur3.Finalize(murmur3.MixHash(h.seed, murmur3.MixKey(sequtil.Hash(k))), 4)
+}
+
+// splitmix64 derives the second half of the SipHash key from the seed.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// sipHash24 computes SipHash-2-4 of p with the key (k0, k1).
+func sipHash24[T string | []byte](k0, k1 uint64, p T) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(p)
+	i := 0
+	for ; i+8 <= n; i += 8 {
+		var m uint64
+		for j := 7; j >= 0; j-- {
+			m = m<<8 | uint64(p[i+j])
+		}
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	b := uint64(n) << 56
+	for j := n - 1; j >= i; j-- {
+		b |= uint64(p[j]) << (8 * uint(j-i))
+	}
+	v3 ^= b
+	round()
+	round()
+	v0 ^= b
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
@@ -0,0 +1,284 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
+	"strings"
+	"testing"
+)
+
+func TestSipHash24Vectors(t *testing.T) {
+	// From the SipHash paper: key 00 01 .. 0f, message 00 01 .. (n-1)
+	const k0, k1 = 0x0706050403020100, 0x0f0e0d0c0b0a0908
+	msg := make([]byte, 15)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	tests := []struct {
+		n   int
+		out uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+	}
+	for i, tt := range tests {
+		if h := sipHash24(k0, k1, msg[:tt.n]); h != tt.out {
+			t.Errorf("%d. sipHash24(%d bytes) => %#x, want %#x", i, tt.n, h, tt.out)
+		}
+		if h := sipHash24(k0, k1, string(msg[:tt.n])); h != tt.out {
+			t.Errorf("%d. sipHash24(%d byte string) => %#x, want %#x", i, tt.n, h, tt.out)
+		}
+	}
+}
+
+// collidingStrings returns 2^n strings with the same Java hashCode, hence the same sequtil.Hash
+func collidingStrings(n int) []string {
+	ret := []string{""}
+	for i := 0; i < n; i++ {
+		var next []string
+		for _, s := range ret {
+			next = append(next, s+"Aa", s+"BB")
+		}
+		ret = next
+	}
+	return ret
+}
+
+func TestSecureHashingSpreadsCollidingStrings(t *testing.T) {
+	keys := collidingStrings(8)
+	if sequtil.Hash(keys[0]) != sequtil.Hash(keys[len(keys)-1]) {
+		t.Fatalf("test strings should collide under sequtil.Hash")
+	}
+
+	m := NewPHashMapWithOptions(HashOptions{Seed: 12345, Secure: true})
+	hashes := make(map[uint32]bool)
+	for i, k := range keys {
+		m = m.AssocM(k, i).(*PHashMap)
+		hashes[m.hasher.Hash(k)] = true
+	}
+	if len(hashes) < len(keys)-2 {
+		t.Errorf("secure hashing gave %d distinct hashes for %d keys", len(hashes), len(keys))
+	}
+	if _, ok := m.root.(*hashCollisionHmnode); ok {
+		t.Errorf("secure map root should not be a collision node")
+	}
+	for i, k := range keys {
+		if v := m.ValAt(k); v != i {
+			t.Errorf("ValAt(%q) => %v, want %d", k, v, i)
+		}
+	}
+}
+
+func TestSecureHashingBytes(t *testing.T) {
+	m := NewPHashMapWithOptions(HashOptions{Seed: 12345, Secure: true})
+	hashes := make(map[uint32]bool)
+	keys := collidingStrings(6)
+	for i, k := range keys {
+		m = m.AssocM([]byte(k), i).(*PHashMap)
+		hashes[m.hasher.Hash([]byte(k))] = true
+	}
+	if len(hashes) < len(keys)-2 {
+		t.Errorf("secure hashing gave %d distinct hashes for %d []byte keys", len(hashes), len(keys))
+	}
+	for i, k := range keys {
+		if v := m.ValAt([]byte(k)); v != i {
+			t.Errorf("ValAt([]byte(%q)) => %v, want %d", k, v, i)
+		}
+	}
+
+	// As documented, a secure map does not find a []byte entry by an equivalent vector
+	b, v := []byte{1, 2}, []int{1, 2}
+	if !sequtil.Equiv(b, v) || sequtil.Hash(b) != sequtil.Hash(v) {
+		t.Fatalf("[]byte and []int should be equivalent and hash alike by default")
+	}
+	if m.hasher.Hash(b) == m.hasher.Hash(v) {
+		t.Errorf("secure hashing should key []byte but not []int")
+	}
+}
+
+func TestSecureEquivSymmetric(t *testing.T) {
+	secure := HashOptions{Seed: 12345, Secure: true}
+	tests := []struct {
+		x, y interface{}
+		out  bool
+	}{
+		{NewPHashMapWithOptions(secure).AssocM([]int{1, 2}, "v"), NewPHashMapFromItems([]byte{1, 2}, "v"), true},
+		{NewPHashMapWithOptions(secure).AssocM([]byte{1, 2}, "v"), NewPHashMapFromItems([]int{1, 2}, "v"), true},
+		{NewPHashMapWithOptions(secure).AssocM([]byte{1, 2}, "v"), NewPHashMapFromItems([]int{1, 3}, "v"), false},
+		{NewPHashSetWithOptions(secure).ConjS([]int{1, 2}), NewPHashSetFromItems([]byte{1, 2}), true},
+		{NewPHashSetWithOptions(secure).ConjS([]byte{1, 2}), NewPHashSetFromItems([]int{1, 2}), true},
+		{NewPHashSetWithOptions(secure).ConjS([]byte{1, 2}), NewPHashSetFromItems([]int{2, 1}), false},
+	}
+	for i, tt := range tests {
+		if e := sequtil.Equiv(tt.x, tt.y); e != tt.out {
+			t.Errorf("%d. Equiv(secure, default) => %v, want %v", i, e, tt.out)
+		}
+		if e := sequtil.Equiv(tt.y, tt.x); e != tt.out {
+			t.Errorf("%d. Equiv(default, secure) => %v, want %v", i, e, tt.out)
+		}
+		if tt.out && sequtil.Hash(tt.x) != sequtil.Hash(tt.y) {
+			t.Errorf("%d. equivalent maps should hash the same", i)
+		}
+	}
+}
+
+func TestSeededMapsAgree(t *testing.T) {
+	opts := []HashOptions{{}, {Seed: 1}, {Seed: 99, Secure: true}}
+	var maps []*PHashMap
+	for _, o := range opts {
+		m := NewPHashMapWithOptions(o)
+		for i := 0; i < 200; i++ {
+			m = m.AssocM(i, strings.Repeat("x", i%7)).(*PHashMap)
+			m = m.AssocM(strings.Repeat("k", i), i).(*PHashMap)
+		}
+		m = m.AssocM(nil, "nil").(*PHashMap)
+		m = m.Without(17).(*PHashMap)
+		maps = append(maps, m)
+	}
+
+	for i, m := range maps {
+		if m.Count() != 400 || sequtil.Count(m.Seq()) != 400 {
+			t.Errorf("%d. Count => %d, seq count %d, want 400", i, m.Count(), sequtil.Count(m.Seq()))
+		}
+		for j, m2 := range maps {
+			if !m.Equiv(m2) {
+				t.Errorf("maps %d and %d should be equivalent", i, j)
+			}
+			if m.Hash() != m2.Hash() {
+				t.Errorf("maps %d and %d hash %d and %d, want equal", i, j, m.Hash(), m2.Hash())
+			}
+		}
+		if e := m.Empty().(*PHashMap); e.hasher != m.hasher {
+			t.Errorf("%d. Empty() should keep the hash options", i)
+		}
+	}
+}
+
+func TestSetDefaultHashOptions(t *testing.T) {
+	SetDefaultHashOptions(HashOptions{Seed: 7, Secure: true})
+	defer SetDefaultHashOptions(HashOptions{})
+
+	m := NewPHashMapFromItems("a", 1, "b", 2)
+	if m.hasher == nil {
+		t.Errorf("factory should use the default hash options")
+	}
+	s := NewPHashSetFromItems("a", "b")
+	if s.impl.hasher == nil {
+		t.Errorf("set factory should use the default hash options")
+	}
+	if !m.Equiv(EmptyPHashMap.AssocM("a", 1).AssocM("b", 2)) || !s.Equiv(NewPHashSetWithOptions(HashOptions{}).ConjS("a").ConjS("b")) {
+		t.Errorf("seeded and unseeded collections should be equivalent")
+	}
+	if EmptyPHashMap.hasher != nil {
+		t.Errorf("EmptyPHashMap should keep the default hashing")
+	}
+}
+
+var caseInsensitive = NewHasher(
+	func(k interface{}) uint32 { return sequtil.Hash(strings.ToLower(k.(string))) },
+	func(k1, k2 interface{}) bool { return strings.EqualFold(k1.(string), k2.(string)) })
+
+type account struct {
+	id   int
+	name string
+}
+
+var byID = NewHasher(
+	func(k interface{}) uint32 { return sequtil.Hash(k.(account).id) },
+	func(k1, k2 interface{}) bool { return k1.(account).id == k2.(account).id })
+
+func TestCustomHasher(t *testing.T) {
+	m := NewPHashMapWithHasher(caseInsensitive).AssocM("Hello", 1).AssocM("WORLD", 2).AssocM("hello", 3)
+	if m.Count() != 2 {
+		t.Errorf("Count => %d, want 2", m.Count())
+	}
+	if v := m.ValAt("HELLO"); v != 3 {
+		t.Errorf("ValAt(HELLO) => %v, want 3", v)
+	}
+	if e := m.EntryAt("world"); e == nil || e.Key() != "WORLD" {
+		t.Errorf("EntryAt(world) => %v, want [WORLD 2]", e)
+	}
+	m = m.Without("hELLo")
+	if m.ContainsKey("Hello") || m.Count() != 1 {
+		t.Errorf("Without(hELLo) left %v", m)
+	}
+	if !m.Empty().(*PHashMap).AssocM("x", 1).ContainsKey("X") {
+		t.Errorf("Empty() should keep the Hasher")
+	}
+
+	// enough keys to build array and collision nodes
+	big := iseq.PMap(NewPHashMapWithHasher(caseInsensitive))
+	for i := 0; i < 1000; i++ {
+		big = big.AssocM(strings.Repeat("a", i%50)+string(rune('A'+i%26))+strings.Repeat("Z", i/26), i)
+	}
+	for i := 0; i < 1000; i++ {
+		k := strings.ToLower(strings.Repeat("a", i%50) + string(rune('A'+i%26)) + strings.Repeat("Z", i/26))
+		if v := big.ValAt(k); v != i {
+			t.Errorf("ValAt(%q) => %v, want %d", k, v, i)
+			break
+		}
+	}
+
+	s := NewPHashSetWithHasher(byID).ConjS(account{1, "ann"}).ConjS(account{2, "bob"}).ConjS(account{1, "anne"})
+	if s.Count() != 2 || s.Get(account{1, ""}) != (account{1, "ann"}) {
+		t.Errorf("set by id => %v", s)
+	}
+}
+
+// exact is finer than sequtil.Equiv:  1 and int64(1) are different keys
+var exact = NewHasher(sequtil.Hash, func(k1, k2 interface{}) bool { return k1 == k2 })
+
+func TestCustomHasherEquiv(t *testing.T) {
+	ci := NewPHashMapWithHasher(caseInsensitive).AssocM("A", 1).AssocM("b", 2)
+	std := NewPHashMapFromItems("A", 1, "b", 2)
+	lower := NewPHashMapWithHasher(caseInsensitive).AssocM("a", 1).AssocM("b", 2)
+
+	tests := []struct {
+		x, y interface{}
+		out  bool
+	}{
+		{ci, std, true},
+		{std, ci, true},
+		{ci, lower, false},
+		{lower, ci, false},
+		{std, lower, false},
+		{lower, NewPHashMapFromItems("a", 1, "b", 2), true},
+		{ci, map[string]int{"A": 1, "b": 2}, true},
+		{NewPHashSetWithHasher(caseInsensitive).ConjS("A"), NewPHashSetFromItems("A"), true},
+		{NewPHashSetFromItems("a"), NewPHashSetWithHasher(caseInsensitive).ConjS("A"), false},
+		{exactMap(1, "a"), NewPHashMapFromItems(int64(1), "a"), true},
+		{NewPHashMapFromItems(int64(1), "a"), exactMap(1, "a"), true},
+		{exactMap(1, "a"), map[int64]string{1: "a"}, true},
+		{exactMap(1, "a", int64(1), "b"), NewPHashMapFromItems(1, "b"), false},
+		{NewPHashMapFromItems(1, "b"), exactMap(1, "a", int64(1), "b"), false},
+		{exactMap(1, "a", int64(1), "a"), exactMap(int64(1), "a", 1, "a"), true},
+		{exactMap(1, "a", int64(1), "a"), exactMap(1, "a", "z", "a"), false},
+		{exactMap(1, "a", "z", "a"), exactMap(1, "a", int64(1), "a"), false},
+		{NewPHashSetWithHasher(exact).ConjS(1).ConjS(int64(1)), NewPHashSetFromItems(1, 2), false},
+		{NewPHashSetFromItems(1, 2), NewPHashSetWithHasher(exact).ConjS(1).ConjS(int64(1)), false},
+		{NewPHashSetWithHasher(exact).ConjS(1).ConjS(int64(1)), NewPHashSetWithHasher(exact).ConjS(int64(1)).ConjS(1), true},
+		{NewPHashSetWithHasher(exact).ConjS(1).ConjS(2), NewPHashSetFromItems(int64(2), int64(1)), true},
+	}
+	for i, tt := range tests {
+		if e := sequtil.Equiv(tt.x, tt.y); e != tt.out {
+			t.Errorf("%d. Equiv(%v, %v) => %v, want %v", i, tt.x, tt.y, e, tt.out)
+		}
+		if tt.out && sequtil.Hash(tt.x) != sequtil.Hash(tt.y) {
+			t.Errorf("%d. equivalent maps should hash the same", i)
+		}
+	}
+}
+
+func exactMap(kvs ...interface{}) *PHashMap {
+	m := iseq.PMap(NewPHashMapWithHasher(exact))
+	for i := 0; i < len(kvs); i += 2 {
+		m = m.AssocM(kvs[i], kvs[i+1])
+	}
+	return m.(*PHashMap)
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
+	"sync"
+)
+
+// Keyword is an interned symbolic identifier, written :name or :ns/name, as in Clojure.
+// There is one *Keyword for each namespace/name pair, so keywords can be compared with ==.
+//
+// A keyword looks itself up in a collection:  k.Get(m) is m.ValAt(k).
+type Keyword struct {
+	sym *Symbol
+}
+
+var keywords sync.Map // *Symbol -> *Keyword
+
+// InternKeyword returns the keyword for nsname (without the leading colon),
+// split into namespace and name as by InternSymbol.
+func InternKeyword(nsname string) *Keyword {
+	return keywordFor(InternSymbol(nsname))
+}
+
+// InternKeywordNS returns the keyword with the given namespace and name.
+// An empty ns means no namespace.
+func InternKeywordNS(ns string, name string) *Keyword {
+	return keywordFor(InternSymbolNS(ns, name))
+}
+
+func keywordFor(sym *Symbol) *Keyword {
+	if k, ok := keywords.Load(sym); ok {
+		return k.(*Keyword)
+	}
+	k, _ := keywords.LoadOrStore(sym, &Keyword{sym})
+	return k.(*Keyword)
+}
+
+// Namespace returns the namespace of the keyword, "" if none.
+func (k *Keyword) Namespace() string {
+	return k.sym.ns
+}
+
+// Name returns the name of the keyword.
+func (k *Keyword) Name() string {
+	return k.sym.name
+}
+
+// Symbol returns the symbol with the keyword's namespace and name.
+func (k *Keyword) Symbol() *Symbol {
+	return k.sym
+}
+
+func (k *Keyword) String() string {
+	return ":" + k.sym.String()
+}
+
+// Get looks the keyword up in coll, an iseq.Lookup such as a map:  (:k coll).
+// Returns nil if coll is not an iseq.Lookup or has no entry for the keyword.
+func (k *Keyword) Get(coll interface{}) interface{} {
+	return k.GetD(coll, nil)
+}
+
+// GetD looks the keyword up in coll:  (:k coll notFound).
+// Returns notFound if coll is not an iseq.Lookup or has no entry for the keyword.
+func (k *Keyword) GetD(coll interface{}, notFound interface{}) interface{} {
+	if l, ok := coll.(iseq.Lookup); ok {
+		return l.ValAtD(k, notFound)
+	}
+	return notFound
+}
+
+// interfaces Equivable, Hashable, Comparer
+
+// Equiv returns true if o is the same keyword.
+func (k *Keyword) Equiv(o interface{}) bool {
+	k2, ok := o.(*Keyword)
+	return ok && k == k2
+}
+
+// Hash matches Clojure's hasheq for keywords:  the symbol's hash plus 0x9e3779b9.
+func (k *Keyword) Hash() uint32 {
+	return k.sym.hash + 0x9e3779b9
+}
+
+// Compare orders keywords by their symbols.
+// Panics with a *sequtil.CompareError if y is not a *Keyword.
+func (k *Keyword) Compare(y interface{}) int {
+	k2, ok := y.(*Keyword)
+	if !ok {
+		panic(&sequtil.CompareError{X: k, Y: y})
+	}
+	return k.sym.Compare(k2.sym)
+}
@@ -0,0 +1,117 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
+	"sort"
+	"testing"
+)
+
+// Values of (hash x) from Clojure 1.6+ on the JVM
+func TestSymbolKeywordHashMatchesClojure(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		out  int32
+	}{
+		{"'a", InternSymbol("a"), -482876059},
+		{"'foo", InternSymbol("foo"), -1385541733},
+		{"'user/foo", InternSymbol("user/foo"), -1391234100},
+		{":a", InternKeyword("a"), -2123407586},
+		{":foo", InternKeyword("foo"), 1268894036},
+		{":user/foo", InternKeywordNS("user", "foo"), 1263201669},
+	}
+	for i, tt := range tests {
+		if h := int32(sequtil.Hash(tt.in)); h != tt.out {
+			t.Errorf("%d. (hash %s) => %d, want %d", i, tt.name, h, tt.out)
+		}
+	}
+}
+
+func TestInterning(t *testing.T) {
+	if InternSymbol("user/foo") != InternSymbolNS("user", "foo") {
+		t.Errorf("symbols with the same ns and name should be identical")
+	}
+	if InternKeyword("a") != InternKeywordNS("", "a") || InternKeyword("a") == InternKeyword("b") {
+		t.Errorf("keywords should be interned by ns and name")
+	}
+	if InternKeyword("a").Symbol() != InternSymbol("a") {
+		t.Errorf("Symbol() should return the interned symbol")
+	}
+	if sequtil.Equiv(InternKeyword("a"), InternSymbol("a")) || sequtil.Equiv(InternKeyword("a"), "a") {
+		t.Errorf("keywords should not be equivalent to symbols or strings")
+	}
+
+	tests := []struct {
+		in       interface{ String() string }
+		ns, name string
+		str      string
+	}{
+		{InternSymbol("a"), "", "a", "a"},
+		{InternSymbol("clojure.core/map"), "clojure.core", "map", "clojure.core/map"},
+		{InternSymbol("/"), "", "/", "/"},
+		{InternSymbol("clojure.core//"), "clojure.core", "/", "clojure.core//"},
+		{InternKeyword("x/y"), "x", "y", ":x/y"},
+	}
+	for i, tt := range tests {
+		var ns, name string
+		switch v := tt.in.(type) {
+		case *Symbol:
+			ns, name = v.Namespace(), v.Name()
+		case *Keyword:
+			ns, name = v.Namespace(), v.Name()
+		}
+		if ns != tt.ns || name != tt.name || tt.in.String() != tt.str {
+			t.Errorf("%d. => ns %q, name %q, %q, want %q, %q, %q", i, ns, name, tt.in.String(), tt.ns, tt.name, tt.str)
+		}
+	}
+}
+
+func TestKeywordCompare(t *testing.T) {
+	ks := []interface{}{InternKeyword("b/a"), InternKeyword("z"), InternKeyword("a/b"), InternKeyword("a"), InternKeyword("a/a")}
+	sort.Slice(ks, func(i, j int) bool { return sequtil.DefaultCompareFn(ks[i], ks[j]) < 0 })
+	want := ":a :z :a/a :a/b :b/a"
+	got := ""
+	for i, k := range ks {
+		if i > 0 {
+			got += " "
+		}
+		got += k.(*Keyword).String()
+	}
+	if got != want {
+		t.Errorf("sorted keywords => %s, want %s", got, want)
+	}
+
+	if _, err := sequtil.DefaultCompareFnE(InternKeyword("a"), InternSymbol("a")); err == nil {
+		t.Errorf("comparing a keyword to a symbol should fail")
+	}
+
+	m := NewPTreeMapFromItems(InternKeyword("b"), 2, InternKeyword("a"), 1)
+	if k := m.Seq().First().(iseq.MapEntry).Key(); k != InternKeyword("a") {
+		t.Errorf("first key of sorted map => %v, want :a", k)
+	}
+}
+
+func TestKeywordLookup(t *testing.T) {
+	a, b := InternKeyword("a"), InternKeyword("b")
+	m := NewPHashMapFromItems(a, 1, "a", "string")
+	if v := a.Get(m); v != 1 {
+		t.Errorf("(:a m) => %v, want 1", v)
+	}
+	if v := b.GetD(m, "none"); v != "none" {
+		t.Errorf("(:b m \"none\") => %v, want none", v)
+	}
+	if v := a.Get(NewPTreeMapFromItems(a, "tree")); v != "tree" {
+		t.Errorf("(:a tree-map) => %v, want tree", v)
+	}
+	if v := a.GetD(42, "none"); v != "none" {
+		t.Errorf("(:a 42 \"none\") => %v, want none", v)
+	}
+	if v := NewPHashMapFromItems(InternKeywordNS("", "a"), 3).ValAt(a); v != 3 {
+		t.Errorf("interned keyword key lookup => %v, want 3", v)
+	}
+}
@@ -6,6 +6,7 @@ package seq
 
 import (
 	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/murmur3"
 	"github.com/dmiller/go-seq/sequtil"
 )
 
@@ -33,7 +34,8 @@ func (m MapEntry) Equiv(o interface{}) bool {
 	return false
 }
 
-// Hash computes a hash value (based only on the key)
+// Hash computes a hash value, as for the vector [key val] (as Clojure does)
 func (m MapEntry) Hash() uint32 {
-	return sequtil.Hash(m.key)
+	hash := 31*(31*1+sequtil.Hash(m.key)) + sequtil.Hash(m.val)
+	return murmur3.FinalizeCollHash(hash, 2)
 }
@@ -5,8 +5,6 @@
 package seq
 
 import (
-	"fmt"
-
 	"github.com/dmiller/go-seq/iseq"
 	"github.com/dmiller/go-seq/sequtil"
 )
@@ -28,6 +26,7 @@ type PHashMap struct {
 	root     hmnode
 	hasNil   bool
 	nilValue interface{}
+	hasher   Hasher
 	AMeta
 	hash uint32
 }
@@ -41,36 +40,64 @@ var (
 
 // TODO: need a factory for creating from an arbitrary Go map
 
+// The factories build maps hashing keys with the options set by SetDefaultHashOptions.
+
 func NewPHashMapFromSeq(items iseq.Seq) *PHashMap {
+	ret, err := NewPHashMapFromSeqE(items)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+func NewPHashMapFromSlice(s []interface{}) *PHashMap {
+	ret, err := NewPHashMapFromSliceE(s)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+func NewPHashMapFromItems(items ...interface{}) *PHashMap {
+	return NewPHashMapFromSlice(items)
+}
+
+// NewPHashMapFromSeqE is NewPHashMapFromSeq returning an *sequtil.ArgumentError if a key has no value.
+func NewPHashMapFromSeqE(items iseq.Seq) (*PHashMap, error) {
 	// TODO: transients
-	ret := EmptyPHashMap
+	ret := defaultEmptyPHashMap()
 
 	for i := 0; items != nil; items, i = items.Next().Next(), i+1 {
 		if items.Next() == nil {
-			panic(fmt.Sprintf("No value supplied for key: %v", items.First()))
+			return nil, &sequtil.ArgumentError{Op: "NewPHashMap", Value: items.First(), Reason: "no value supplied for key"}
 		}
 		ret = ret.AssocM(items.First(), items.Next().First()).(*PHashMap)
 		// if checkDup && ret.Count1() != i+1 {
 		// 	panic(fmt.Sprintf("Duplicate key: %v",items.First()))
 		// }
 	}
-	return ret
+	return ret, nil
 }
 
-func NewPHashMapFromSlice(s []interface{}) *PHashMap {
+// NewPHashMapFromSliceE is NewPHashMapFromSlice returning an *sequtil.ArgumentError for an odd-length slice.
+func NewPHashMapFromSliceE(s []interface{}) (*PHashMap, error) {
+	if len(s)%2 != 0 {
+		return nil, &sequtil.ArgumentError{Op: "NewPHashMap", Value: s[len(s)-1], Reason: "no value supplied for key"}
+	}
 	// TODO: transients
-	ret := EmptyPHashMap
+	ret := defaultEmptyPHashMap()
 	for i := 0; i < len(s); i = i + 2 {
 		ret = ret.AssocM(s[i], s[i+1]).(*PHashMap)
 		// if checkDup && ret.Count1() != i+1 {
 		// 	panic(fmt.Sprintf("Duplicate key: %v",s[i]))
 		// }
 	}
-	return ret
+	return ret, nil
 }
 
-func NewPHashMapFromItems(items ...interface{}) *PHashMap {
-	return NewPHashMapFromSlice(items)
+// NewPHashMapFromItemsE is NewPHashMapFromItems returning an *sequtil.ArgumentError for an odd number of items.
+func NewPHashMapFromItemsE(items ...interface{}) (*PHashMap, error) {
+	return NewPHashMapFromSliceE(items)
 }
 
 // PHashMap needs to implement the following iseq interfaces:
@@ -88,7 +115,8 @@ func (m *PHashMap) WithMeta(meta iseq.PMap) iseq.MetaW {
 		count:    m.count,
 		root:     m.root,
 		hasNil:   m.hasNil,
-		nilValue: m.nilValue}
+		nilValue: m.nilValue,
+		hasher:   m.hasher}
 }
 
 // interface iseq.Associative, iseq.Lookup
@@ -102,7 +130,8 @@ func (m *PHashMap) ContainsKey(key interface{}) bool {
 		return false
 	}
 
-	return m.root.findD(0, Hash(key), key, phmNotFoundValue) != phmNotFoundValue
+	kh := m.keys()
+	return m.root.findD(kh, 0, kh.Hash(key), key, phmNotFoundValue) != phmNotFoundValue
 }
 
 func (m *PHashMap) EntryAt(key interface{}) iseq.MapEntry {
@@ -116,7 +145,8 @@ func (m *PHashMap) EntryAt(key interface{}) iseq.MapEntry {
 		return nil
 	}
 
-	return m.root.find(0, Hash(key), key)
+	kh := m.keys()
+	return m.root.find(kh, 0, kh.Hash(key), key)
 }
 
 func (m *PHashMap) Assoc(key interface{}, val interface{}) iseq.Associative {
@@ -139,7 +169,8 @@ func (m *PHashMap) ValAtD(key interface{}, notFound interface{}) interface{} {
 		return notFound
 	}
 
-	return m.root.findD(0, Hash(key), key, notFound)
+	kh := m.keys()
+	return m.root.findD(kh, 0, kh.Hash(key), key, notFound)
 }
 
 // interface iseq.PMap
@@ -157,13 +188,15 @@ func (m *PHashMap) AssocM(key interface{}, val interface{}) iseq.PMap {
 			count:    newCount,
 			root:     m.root,
 			hasNil:   true,
-			nilValue: val}
+			nilValue: val,
+			hasher:   m.hasher}
 	}
 	newRoot := m.root
 	if newRoot == nil {
 		newRoot = emptyBitmapIndexedHmnode
 	}
-	newRoot, addedLeaf := newRoot.assoc2(0, Hash(key), key, val)
+	kh := m.keys()
+	newRoot, addedLeaf := newRoot.assoc2(kh, 0, kh.Hash(key), key, val)
 	if newRoot == m.root {
 		return m
 	}
@@ -175,7 +208,8 @@ func (m *PHashMap) AssocM(key interface{}, val interface{}) iseq.PMap {
 		count:    newCount,
 		root:     newRoot,
 		hasNil:   m.hasNil,
-		nilValue: m.nilValue}
+		nilValue: m.nilValue,
+		hasher:   m.hasher}
 }
 
 func (m *PHashMap) Without(key interface{}) iseq.PMap {
@@ -185,11 +219,13 @@ func (m *PHashMap) Without(key interface{}) iseq.PMap {
 				count:    m.count - 1,
 				root:     m.root,
 				hasNil:   false,
-				nilValue: nil}
+				nilValue: nil,
+				hasher:   m.hasher}
 		}
 		return m
 	}
-	newRoot := m.root.without(0, Hash(key), key)
+	kh := m.keys()
+	newRoot := m.root.without(kh, 0, kh.Hash(key), key)
 	if newRoot == m.root {
 		return m
 	}
@@ -197,7 +233,8 @@ func (m *PHashMap) Without(key interface{}) iseq.PMap {
 		count:    m.count - 1,
 		root:     newRoot,
 		hasNil:   m.hasNil,
-		nilValue: m.nilValue}
+		nilValue: m.nilValue,
+		hasher:   m.hasher}
 }
 
 func (m *PHashMap) ConsM(e iseq.MapEntry) iseq.PMap {
@@ -219,7 +256,7 @@ func (m *PHashMap) Cons(o interface{}) iseq.PCollection {
 }
 
 func (m *PHashMap) Empty() iseq.PCollection {
-	return EmptyPHashMap.WithMeta(m.Meta()).(iseq.PCollection)
+	return &PHashMap{AMeta: AMeta{m.Meta()}, hasher: m.hasher}
 }
 
 func (m *PHashMap) Seq() iseq.Seq {
@@ -235,8 +272,24 @@ func (m *PHashMap) Seq() iseq.Seq {
 
 // interfaces Equivable, Hashable
 
+// Equiv compares keys with sequtil.Equiv, whatever the Hashers of the maps.  See Hasher.
 func (m *PHashMap) Equiv(o interface{}) bool {
-	return sequtil.MapEquiv(m, o)
+	if m == o {
+		return true
+	}
+	m2, ok := o.(*PHashMap)
+	if m.hasStandardKeys() && (!ok || m2.hasStandardKeys()) {
+		return sequtil.MapEquiv(m, o)
+	}
+
+	// A custom Hasher can't look keys up by sequtil.Equiv, so match the entries themselves.
+	if om, ok := o.(iseq.PMap); ok {
+		return m.Count() == om.Count() && sameItems(m.Seq(), om.Seq(), entryKey, entryEquiv)
+	}
+	if sequtil.IsGoMap(o) {
+		return sameItems(m.Seq(), NewMapSeq(o), entryKey, entryEquiv)
+	}
+	return false
 }
 
 func (p *PHashMap) Hash() uint32 {
@@ -249,11 +302,11 @@ func (p *PHashMap) Hash() uint32 {
 // Nodes in the trie
 
 type hmnode interface {
-	assoc(shift uint32, hash uint32, key interface{}, val interface{}) hmnode
-	assoc2(shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool)
-	without(shift uint32, hash uint32, key interface{}) hmnode
-	find(shift uint32, hash uint32, key interface{}) iseq.MapEntry
-	findD(shift uint32, hash uint32, key interface{}, notFound interface{}) interface{}
+	assoc(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) hmnode
+	assoc2(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool)
+	without(kh Hasher, shift uint32, hash uint32, key interface{}) hmnode
+	find(kh Hasher, shift uint32, hash uint32, key interface{}) iseq.MapEntry
+	findD(kh Hasher, shift uint32, hash uint32, key interface{}, notFound interface{}) interface{}
 	getNodeSeq() iseq.Seq
 	//getHash() uint32 -- in the Java code, but does not appear to be used
 }
@@ -291,12 +344,12 @@ func removePair(src []interface{}, i int) []interface{} {
 
 // Node factories
 
-func createNode(shift uint32, key1 interface{}, val1 interface{}, key2hash uint32, key2 interface{}, val2 interface{}) hmnode {
-	key1hash := Hash(key1)
+func createNode(kh Hasher, shift uint32, key1 interface{}, val1 interface{}, key2hash uint32, key2 interface{}, val2 interface{}) hmnode {
+	key1hash := kh.Hash(key1)
 	if key1hash == key2hash {
 		return &hashCollisionHmnode{key1hash, 2, []interface{}{key1, val1, key2, val2}}
 	}
-	node := emptyBitmapIndexedHmnode.assoc(shift, key1hash, key1, val1).assoc(shift, key2hash, key2, val2)
+	node := emptyBitmapIndexedHmnode.assoc(kh, shift, key1hash, key1, val1).assoc(kh, shift, key2hash, key2, val2)
 	return node
 }
 
@@ -320,32 +373,32 @@ type arrayHmnode struct {
 	array []hmnode
 }
 
-func (a *arrayHmnode) assoc(shift uint32, hash uint32, key interface{}, val interface{}) hmnode {
-	node, _ := a.assoc2(shift, hash, key, val)
+func (a *arrayHmnode) assoc(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) hmnode {
+	node, _ := a.assoc2(kh, shift, hash, key, val)
 	return node
 }
 
-func (a *arrayHmnode) assoc2(shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool) {
+func (a *arrayHmnode) assoc2(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool) {
 	idx := imask(hash, shift)
 	node := a.array[idx]
 	if node == nil {
-		newNode, addedLeaf := emptyBitmapIndexedHmnode.assoc2(shift+5, hash, key, val)
+		newNode, addedLeaf := emptyBitmapIndexedHmnode.assoc2(kh, shift+5, hash, key, val)
 		return &arrayHmnode{a.count + 1, cloneAndSetNodeSlice(a.array, idx, newNode)}, addedLeaf
 	}
-	anode, addedLeaf := node.assoc2(shift+5, hash, key, val)
+	anode, addedLeaf := node.assoc2(kh, shift+5, hash, key, val)
 	if anode == node {
 		return a, addedLeaf
 	}
 	return &arrayHmnode{a.count, cloneAndSetNodeSlice(a.array, idx, anode)}, addedLeaf
 }
 
-func (a *arrayHmnode) without(shift uint32, hash uint32, key interface{}) hmnode {
+func (a *arrayHmnode) without(kh Hasher, shift uint32, hash uint32, key interface{}) hmnode {
 	idx := imask(hash, shift)
 	node := a.array[idx]
 	if node == nil {
 		return a
 	}
-	n := node.without(shift+5, hash, key)
+	n := node.without(kh, shift+5, hash, key)
 	if n == node {
 		return a
 	}
@@ -358,22 +411,22 @@ func (a *arrayHmnode) without(shift uint32, hash uint32, key interface{}) hmnode
 	return &arrayHmnode{a.count, cloneAndSetNodeSlice(a.array, idx, n)}
 }
 
-func (a *arrayHmnode) find(shift uint32, hash uint32, key interface{}) iseq.MapEntry {
+func (a *arrayHmnode) find(kh Hasher, shift uint32, hash uint32, key interface{}) iseq.MapEntry {
 	idx := imask(hash, shift)
 	node := a.array[idx]
 	if node == nil {
 		return nil
 	}
-	return node.find(shift+5, hash, key)
+	return node.find(kh, shift+5, hash, key)
 }
 
-func (a *arrayHmnode) findD(shift uint32, hash uint32, key interface{}, notFound interface{}) interface{} {
+func (a *arrayHmnode) findD(kh Hasher, shift uint32, hash uint32, key interface{}, notFound interface{}) interface{} {
 	idx := imask(hash, shift)
 	node := a.array[idx]
 	if node == nil {
 		return notFound
 	}
-	return node.findD(shift+5, hash, key, notFound)
+	return node.findD(kh, shift+5, hash, key, notFound)
 
 }
 
@@ -415,7 +468,8 @@ type arrayHmnodeSeq struct {
 	AMeta
 }
 
-func createArrayHmnodeSeq(meta iseq.PMap, nodes []hmnode, i int, s iseq.Seq) *arrayHmnodeSeq {
+// Returns an untyped nil at the end, so that callers' nil checks on iseq.Seq work
+func createArrayHmnodeSeq(meta iseq.PMap, nodes []hmnode, i int, s iseq.Seq) iseq.Seq {
 	if s != nil {
 		return &arrayHmnodeSeq{AMeta: AMeta{meta}, nodes: nodes, i: i, s: s}
 	}
@@ -431,7 +485,7 @@ func createArrayHmnodeSeq(meta iseq.PMap, nodes []hmnode, i int, s iseq.Seq) *ar
 }
 
 func (a *arrayHmnodeSeq) WithMeta(meta iseq.PMap) iseq.MetaW {
-	return createArrayHmnodeSeq(meta, a.nodes, a.i, a.s)
+	return &arrayHmnodeSeq{AMeta: AMeta{meta}, nodes: a.nodes, i: a.i, s: a.s}
 }
 
 func (a *arrayHmnodeSeq) First() interface{} {
@@ -463,7 +517,7 @@ func (a *arrayHmnodeSeq) Empty() iseq.PCollection {
 }
 
 func (a *arrayHmnodeSeq) Equiv(o interface{}) bool {
-	return sequtil.Equiv(a, o)
+	return sequtil.SequentialEquiv(a, o)
 }
 
 func (a *arrayHmnodeSeq) Seq() iseq.Seq {
@@ -482,12 +536,12 @@ func (b *bitmapIndexedHmnode) index(bit uint32) int {
 	return sequtil.BitCountU32(b.bitmap & (bit - 1))
 }
 
-func (b *bitmapIndexedHmnode) assoc(shift uint32, hash uint32, key interface{}, val interface{}) hmnode {
-	node, _ := b.assoc2(shift, hash, key, val)
+func (b *bitmapIndexedHmnode) assoc(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) hmnode {
+	node, _ := b.assoc2(kh, shift, hash, key, val)
 	return node
 }
 
-func (b *bitmapIndexedHmnode) assoc2(shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool) {
+func (b *bitmapIndexedHmnode) assoc2(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool) {
 	bit := bitpos(hash, shift)
 	idx := b.index(bit)
 	if (b.bitmap & bit) != 0 {
@@ -498,26 +552,26 @@ func (b *bitmapIndexedHmnode) assoc2(shift uint32, hash uint32, key interface{},
 			if !ok {
 				panic("Unexpected node type")
 			}
-			n, addedLeaf := n.assoc2(shift+5, hash, key, val)
+			n, addedLeaf := n.assoc2(kh, shift+5, hash, key, val)
 			if n == valOrNode {
 				return b, false
 			}
 			return &bitmapIndexedHmnode{b.bitmap, cloneAndSetObjectSlice(b.array, 2*idx+1, n)}, addedLeaf
 		}
-		if sequtil.Equiv(key, keyOrNil) {
+		if kh.Equiv(key, keyOrNil) {
 			if val == valOrNode {
 				return b, false
 			}
 			return &bitmapIndexedHmnode{b.bitmap, cloneAndSetObjectSlice(b.array, 2*idx+1, val)}, false
 		}
-		return &bitmapIndexedHmnode{b.bitmap, cloneAndSetObjectSlice2(b.array, 2*idx, nil, 2*idx+1, createNode(shift+5, keyOrNil, valOrNode, hash, key, val))}, true
+		return &bitmapIndexedHmnode{b.bitmap, cloneAndSetObjectSlice2(b.array, 2*idx, nil, 2*idx+1, createNode(kh, shift+5, keyOrNil, valOrNode, hash, key, val))}, true
 	}
 
 	n := sequtil.BitCountU32(b.bitmap)
 	if n >= 16 {
 		nodes := make([]hmnode, 32)
 		jdx := imask(hash, shift)
-		nodes[jdx] = emptyBitmapIndexedHmnode.assoc(shift+5, hash, key, val)
+		nodes[jdx] = emptyBitmapIndexedHmnode.assoc(kh, shift+5, hash, key, val)
 		for i, j := 0, 0; i < 32; i++ {
 			if ((b.bitmap >> uint(i)) & 1) != 0 {
 				if b.array[j] == nil {
@@ -527,7 +581,7 @@ func (b *bitmapIndexedHmnode) assoc2(shift uint32, hash uint32, key interface{},
 						panic("Unexpected node type")
 					}
 				} else {
-					nodes[i] = emptyBitmapIndexedHmnode.assoc(shift+5, Hash(b.array[j]), b.array[j], b.array[j+1])
+					nodes[i] = emptyBitmapIndexedHmnode.assoc(kh, shift+5, kh.Hash(b.array[j]), b.array[j], b.array[j+1])
 				}
 				j += 2
 			}
@@ -543,7 +597,7 @@ func (b *bitmapIndexedHmnode) assoc2(shift uint32, hash uint32, key interface{},
 	return &bitmapIndexedHmnode{b.bitmap | bit, newArray}, true
 }
 
-func (b *bitmapIndexedHmnode) without(shift uint32, hash uint32, key interface{}) hmnode {
+func (b *bitmapIndexedHmnode) without(kh Hasher, shift uint32, hash uint32, key interface{}) hmnode {
 	bit := bitpos(hash, shift)
 	if (b.bitmap & bit) == 0 {
 		return b
@@ -553,7 +607,7 @@ func (b *bitmapIndexedHmnode) without(shift uint32, hash uint32, key interface{}
 	keyOrNil := b.array[2*idx]
 	valOrNode := b.array[2*idx+1]
 	if keyOrNil == nil {
-		n := valOrNode.(hmnode).without(shift+5, hash, key)
+		n := valOrNode.(hmnode).without(kh, shift+5, hash, key)
 		// TOOD: use switch
 		if n == valOrNode {
 			return b
@@ -566,14 +620,14 @@ func (b *bitmapIndexedHmnode) without(shift uint32, hash uint32, key interface{}
 		}
 		return &bitmapIndexedHmnode{b.bitmap ^ bit, removePair(b.array, idx)}
 	}
-	if sequtil.Equiv(key, keyOrNil) {
+	if kh.Equiv(key, keyOrNil) {
 		// TODO: Collapse  (TODO in Java code)
 		return &bitmapIndexedHmnode{b.bitmap ^ bit, removePair(b.array, idx)}
 	}
 	return b
 }
 
-func (b *bitmapIndexedHmnode) find(shift uint32, hash uint32, key interface{}) iseq.MapEntry {
+func (b *bitmapIndexedHmnode) find(kh Hasher, shift uint32, hash uint32, key interface{}) iseq.MapEntry {
 	bit := bitpos(hash, shift)
 	if (b.bitmap & bit) == 0 {
 		return nil
@@ -584,15 +638,15 @@ func (b *bitmapIndexedHmnode) find(shift uint32, hash uint32, key interface{}) i
 	keyOrNil := b.array[2*idx]
 	valOrNode := b.array[2*idx+1]
 	if keyOrNil == nil {
-		return valOrNode.(hmnode).find(shift+5, hash, key)
+		return valOrNode.(hmnode).find(kh, shift+5, hash, key)
 	}
-	if sequtil.Equiv(key, keyOrNil) {
+	if kh.Equiv(key, keyOrNil) {
 		return MapEntry{keyOrNil, valOrNode}
 	}
 	return nil
 }
 
-func (b *bitmapIndexedHmnode) findD(shift uint32, hash uint32, key interface{}, notFound interface{}) interface{} {
+func (b *bitmapIndexedHmnode) findD(kh Hasher, shift uint32, hash uint32, key interface{}, notFound interface{}) interface{} {
 	bit := bitpos(hash, shift)
 	if (b.bitmap & bit) == 0 {
 		return notFound
@@ -603,9 +657,9 @@ func (b *bitmapIndexedHmnode) findD(shift uint32, hash uint32, key interface{},
 	keyOrNil := b.array[2*idx]
 	valOrNode := b.array[2*idx+1]
 	if keyOrNil == nil {
-		return valOrNode.(hmnode).findD(shift+5, hash, key, notFound)
+		return valOrNode.(hmnode).findD(kh, shift+5, hash, key, notFound)
 	}
-	if sequtil.Equiv(key, keyOrNil) {
+	if kh.Equiv(key, keyOrNil) {
 		return valOrNode
 	}
 	return notFound
@@ -626,23 +680,23 @@ type hashCollisionHmnode struct {
 	array []interface{}
 }
 
-func (h *hashCollisionHmnode) findIndex(key interface{}) int {
+func (h *hashCollisionHmnode) findIndex(kh Hasher, key interface{}) int {
 	for i := 0; i < 2*h.count; i = i + 2 {
-		if sequtil.Equiv(key, h.array[i]) {
+		if kh.Equiv(key, h.array[i]) {
 			return i
 		}
 	}
 	return -1
 }
 
-func (h *hashCollisionHmnode) assoc(shift uint32, hash uint32, key interface{}, val interface{}) hmnode {
-	node, _ := h.assoc2(shift, hash, key, val)
+func (h *hashCollisionHmnode) assoc(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) hmnode {
+	node, _ := h.assoc2(kh, shift, hash, key, val)
 	return node
 }
 
-func (h *hashCollisionHmnode) assoc2(shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool) {
+func (h *hashCollisionHmnode) assoc2(kh Hasher, shift uint32, hash uint32, key interface{}, val interface{}) (hmnode, bool) {
 	if h.hash == hash {
-		idx := h.findIndex(key)
+		idx := h.findIndex(kh, key)
 		if idx != -1 {
 			if h.array[idx+1] == val {
 				return h, false
@@ -656,12 +710,12 @@ func (h *hashCollisionHmnode) assoc2(shift uint32, hash uint32, key interface{},
 		return &hashCollisionHmnode{hash, h.count + 1, newArray}, true
 	}
 	// nest it in a bitmap node
-	ret, addedLeaf := (&bitmapIndexedHmnode{bitpos(h.hash, shift), []interface{}{nil, h}}).assoc2(shift, hash, key, val)
+	ret, addedLeaf := (&bitmapIndexedHmnode{bitpos(h.hash, shift), []interface{}{nil, h}}).assoc2(kh, shift, hash, key, val)
 	return ret, addedLeaf
 }
 
-func (h *hashCollisionHmnode) without(shift uint32, hash uint32, key interface{}) hmnode {
-	idx := h.findIndex(key)
+func (h *hashCollisionHmnode) without(kh Hasher, shift uint32, hash uint32, key interface{}) hmnode {
+	idx := h.findIndex(kh, key)
 	// TOOD: use switch
 	if idx == -1 {
 		return h
@@ -673,23 +727,23 @@ func (h *hashCollisionHmnode) without(shift uint32, hash uint32, key interface{}
 
 }
 
-func (h *hashCollisionHmnode) find(shift uint32, hash uint32, key interface{}) iseq.MapEntry {
-	idx := h.findIndex(key)
+func (h *hashCollisionHmnode) find(kh Hasher, shift uint32, hash uint32, key interface{}) iseq.MapEntry {
+	idx := h.findIndex(kh, key)
 	if idx < 0 {
 		return nil
 	}
-	if sequtil.Equiv(key, h.array[idx]) {
+	if kh.Equiv(key, h.array[idx]) {
 		return &MapEntry{h.array[idx], h.array[idx+1]}
 	}
 	return nil
 }
 
-func (h *hashCollisionHmnode) findD(shift uint32, hash uint32, key interface{}, notFound interface{}) interface{} {
-	idx := h.findIndex(key)
+func (h *hashCollisionHmnode) findD(kh Hasher, shift uint32, hash uint32, key interface{}, notFound interface{}) interface{} {
+	idx := h.findIndex(kh, key)
 	if idx < 0 {
 		return notFound
 	}
-	if sequtil.Equiv(key, h.array[idx]) {
+	if kh.Equiv(key, h.array[idx]) {
 		return h.array[idx+1]
 	}
 	return notFound
@@ -711,31 +765,31 @@ type hmnodeSeq struct {
 	AMeta
 }
 
-func createHmnodeSeq(array []interface{}) *hmnodeSeq {
+func createHmnodeSeq(array []interface{}) iseq.Seq {
 	return createHmnodeSeq3(array, 0, nil)
 }
 
-func createHmnodeSeq3(array []interface{}, i int, s iseq.Seq) *hmnodeSeq {
+// Returns an untyped nil at the end, so that callers' nil checks on iseq.Seq work
+func createHmnodeSeq3(array []interface{}, i int, s iseq.Seq) iseq.Seq {
 	if s != nil {
 		return &hmnodeSeq{array: array, i: i, s: s}
 	}
-	for j := i; i < len(array); j = j + 2 {
+	for j := i; j < len(array); j = j + 2 {
 		if array[j] != nil {
 			return &hmnodeSeq{array: array, i: j, s: nil}
 		}
-		// TODO: nil comparison on interface: fix
+		if array[j+1] == nil {
+			continue
+		}
 		node, ok := array[j+1].(hmnode)
 		if !ok {
 			panic("Bad node type")
 		}
-		if node != nil {
-			if nodeSeq := node.getNodeSeq(); nodeSeq != nil {
-				return &hmnodeSeq{array: array, i: j + 2, s: nodeSeq}
-			}
+		if nodeSeq := node.getNodeSeq(); nodeSeq != nil {
+			return &hmnodeSeq{array: array, i: j + 2, s: nodeSeq}
 		}
 	}
 	return nil
-
 }
 
 // hmnodeSeq must implement the following iseq interfaces:
@@ -768,7 +822,7 @@ func (h *hmnodeSeq) Empty() iseq.PCollection {
 
 // TODO: Check to make sure not a loop
 func (h *hmnodeSeq) Equiv(o interface{}) bool {
-	return sequtil.Equiv(h, o)
+	return sequtil.SequentialEquiv(h, o)
 }
 
 // interface iseq.Seq
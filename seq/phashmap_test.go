@@ -5,8 +5,10 @@
 package seq
 
 import (
+	"errors"
 	"fmt"
 	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
 	"math/rand"
 	"testing"
 )
@@ -397,3 +399,49 @@ func TestPHashMapWithout(t *testing.T) {
 }
 
 // TODO: Finish tests
+
+func TestPHashMapErrorVariants(t *testing.T) {
+	var ae *sequtil.ArgumentError
+	if _, err := NewPHashMapFromItemsE("a", 1, "b"); !errors.As(err, &ae) || ae.Value != "b" {
+		t.Errorf("NewPHashMapFromItemsE with odd items => %v, want an ArgumentError for b", err)
+	}
+	if _, err := NewPHashMapFromSeqE(NewPListFromSlice([]interface{}{"a"})); !errors.As(err, &ae) || ae.Value != "a" {
+		t.Errorf("NewPHashMapFromSeqE with odd items => %v, want an ArgumentError for a", err)
+	}
+	if m, err := NewPHashMapFromSliceE([]interface{}{"a", 1}); err != nil || m.ValAt("a") != 1 {
+		t.Errorf("NewPHashMapFromSliceE => %v, %v", m, err)
+	}
+
+	m := NewPHashMapFromItems("a", 1)
+	if _, err := sequtil.MapConsE(m, NewPVectorFromItems(1, 2, 3)); !errors.As(err, &ae) {
+		t.Errorf("MapConsE with a triple => %v, want an ArgumentError", err)
+	}
+	var te *sequtil.TypeError
+	if _, err := sequtil.MapConsE(m, 42); !errors.As(err, &te) {
+		t.Errorf("MapConsE(42) => %v, want a TypeError", err)
+	}
+	if _, err := sequtil.MapConsE(m, NewPListFromSlice([]interface{}{"x"})); !errors.As(err, &te) || te.Value != "x" {
+		t.Errorf("MapConsE on a list of non-entries => %v, want a TypeError", err)
+	}
+	if r, err := sequtil.MapConsE(m, NewPVectorFromItems("b", 2)); err != nil || r.Count() != 2 {
+		t.Errorf("MapConsE([b 2]) => %v, %v", r, err)
+	}
+}
+
+func TestPHashMapKeysHoldingSlices(t *testing.T) {
+	// [1]interface{} is a comparable type, but == panics when it holds a slice
+	k1, k2 := [1]interface{}{[]int{1}}, [1]interface{}{[]int{2}}
+	m := NewPHashMapFromItems(k1, "one", k2, "two")
+	if v := m.ValAt([1]interface{}{[]int{1}}); v != "one" {
+		t.Errorf("ValAt(%v) => %v, want one", k1, v)
+	}
+	if m.ContainsKey([1]interface{}{[]int{3}}) {
+		t.Errorf("ContainsKey of a missing key => true")
+	}
+	if !m.Equiv(NewPHashMapFromItems(k2, "two", k1, "one")) {
+		t.Errorf("maps with slice-holding keys should be equivalent")
+	}
+	if !NewPHashSetFromItems(k1, k2).Contains([1]interface{}{[]int{2}}) {
+		t.Errorf("set should contain %v", k2)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/sequtil"
+)
+
+// PHashSet is a persistent set, implemented as a PHashMap mapping each element to itself.
+type PHashSet struct {
+	impl *PHashMap
+	AMeta
+	hash uint32
+}
+
+var EmptyPHashSet = &PHashSet{impl: EmptyPHashMap}
+
+// PHashSet needs to implement the following iseq interfaces:
+//	Meta MetaW Seqable PCollection Counted PSet
+//  Also, Equivable and Hashable
+//
+// interface Meta is covered by the AMeta embedding
+
+// factories
+
+// NewPHashSetFromSlice returns a PHashSet containing the elements of the slice.
+func NewPHashSetFromSlice(s []interface{}) *PHashSet {
+	ret := defaultEmptyPHashSet()
+	for _, x := range s {
+		ret = ret.ConjS(x)
+	}
+	return ret
+}
+
+// NewPHashSetFromItems returns a PHashSet containing its arguments.
+func NewPHashSetFromItems(items ...interface{}) *PHashSet {
+	return NewPHashSetFromSlice(items)
+}
+
+// NewPHashSetFromSeq returns a PHashSet containing the elements of the seq.
+func NewPHashSetFromSeq(items iseq.Seq) *PHashSet {
+	ret := defaultEmptyPHashSet()
+	for ; items != nil; items = items.Next() {
+		ret = ret.ConjS(items.First())
+	}
+	return ret
+}
+
+// NewPHashSetWithOptions returns an empty PHashSet hashing its elements as specified.  See HashOptions.
+func NewPHashSetWithOptions(opts HashOptions) *PHashSet {
+	return &PHashSet{impl: NewPHashMapWithOptions(opts)}
+}
+
+// NewPHashSetWithHasher returns an empty PHashSet using h for its elements.  See Hasher.
+func NewPHashSetWithHasher(h Hasher) *PHashSet {
+	return &PHashSet{impl: NewPHashMapWithHasher(h)}
+}
+
+func defaultEmptyPHashSet() *PHashSet {
+	if defaultHasher.Load() == nil {
+		return EmptyPHashSet
+	}
+	return &PHashSet{impl: defaultEmptyPHashMap()}
+}
+
+func (s *PHashSet) make(impl *PHashMap) *PHashSet {
+	if impl == s.impl {
+		return s
+	}
+	return &PHashSet{AMeta: AMeta{s.Meta()}, impl: impl}
+}
+
+// interface iseq.MetaW
+
+func (s *PHashSet) WithMeta(meta iseq.PMap) iseq.MetaW {
+	return &PHashSet{AMeta: AMeta{meta}, impl: s.impl}
+}
+
+// interface iseq.PSet
+
+// Contains returns true if key is an element of the set.
+func (s *PHashSet) Contains(key interface{}) bool {
+	return s.impl.ContainsKey(key)
+}
+
+// Get returns the element of the set equivalent to key, or nil if there is none.
+func (s *PHashSet) Get(key interface{}) interface{} {
+	return s.impl.ValAt(key)
+}
+
+// Disjoin returns a set without key.
+func (s *PHashSet) Disjoin(key interface{}) iseq.PSet {
+	return s.DisjoinS(key)
+}
+
+// DisjoinS returns a set without key.  Type-specific version of Disjoin.
+func (s *PHashSet) DisjoinS(key interface{}) *PHashSet {
+	return s.make(s.impl.Without(key).(*PHashMap))
+}
+
+// ConjS returns a set with key added.  Type-specific version of Cons.
+func (s *PHashSet) ConjS(key interface{}) *PHashSet {
+	if s.impl.ContainsKey(key) {
+		return s
+	}
+	return s.make(s.impl.AssocM(key, key).(*PHashMap))
+}
+
+// interface iseq.PCollection, iseq.Seqable, iseq.Counted
+
+func (s *PHashSet) Count() int {
+	return s.impl.Count()
+}
+
+func (s *PHashSet) Count1() int {
+	return s.impl.Count()
+}
+
+func (s *PHashSet) Cons(o interface{}) iseq.PCollection {
+	return s.ConjS(o)
+}
+
+func (s *PHashSet) Empty() iseq.PCollection {
+	return &PHashSet{AMeta: AMeta{s.Meta()}, impl: s.impl.Empty().(*PHashMap)}
+}
+
+func (s *PHashSet) Seq() iseq.Seq {
+	return newKeySeq(s.impl.Seq())
+}
+
+// interfaces Equivable, Hashable
+
+// Equiv returns true if o is a set with equivalent elements.
+func (s *PHashSet) Equiv(o interface{}) bool {
+	if s == o {
+		return true
+	}
+	os, ok := o.(iseq.PSet)
+	if !ok || os.Count() != s.Count() {
+		return false
+	}
+	if s2, ok := o.(*PHashSet); !s.impl.hasStandardKeys() || ok && !s2.impl.hasStandardKeys() {
+		// A custom Hasher can't look elements up by sequtil.Equiv, so match the elements themselves.
+		return sameItems(s.Seq(), os.Seq(), itself, sequtil.Equiv)
+	}
+	for x := s.Seq(); x != nil; x = x.Next() {
+		if !os.Contains(x.First()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *PHashSet) Hash() uint32 {
+	if s.hash == 0 {
+		s.hash = sequtil.HashUnordered(s.Seq())
+	}
+	return s.hash
+}
+
+// A keySeq is a seq of the keys of a seq of MapEntrys.
+type keySeq struct {
+	AMeta
+	s iseq.Seq
+}
+
+func newKeySeq(s iseq.Seq) iseq.Seq {
+	if s == nil {
+		return nil
+	}
+	return &keySeq{s: s}
+}
+
+func (k *keySeq) WithMeta(meta iseq.PMap) iseq.MetaW {
+	return &keySeq{AMeta: AMeta{meta}, s: k.s}
+}
+
+func (k *keySeq) First() interface{} {
+	return k.s.First().(iseq.MapEntry).Key()
+}
+
+func (k *keySeq) Next() iseq.Seq {
+	return newKeySeq(k.s.Next())
+}
+
+func (k *keySeq) More() iseq.Seq {
+	if next := k.Next(); next != nil {
+		return next
+	}
+	return CachedEmptyList
+}
+
+func (k *keySeq) Cons(o interface{}) iseq.PCollection {
+	return k.ConsS(o)
+}
+
+func (k *keySeq) ConsS(o interface{}) iseq.Seq {
+	return NewCons(o, k)
+}
+
+func (k *keySeq) Count() int {
+	return sequtil.Count(k.s)
+}
+
+func (k *keySeq) Empty() iseq.PCollection {
+	return CachedEmptyList
+}
+
+func (k *keySeq) Equiv(o interface{}) bool {
+	return sequtil.SequentialEquiv(k, o)
+}
+
+func (k *keySeq) Seq() iseq.Seq {
+	return k
+}
@@ -0,0 +1,117 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"testing"
+)
+
+func TestPHashSetImplementInterfaces(t *testing.T) {
+	var c interface{} = NewPHashSetFromItems("abc", "def")
+
+	if _, ok := c.(iseq.MetaW); !ok {
+		t.Error("PHashSet must implement MetaW")
+	}
+
+	if _, ok := c.(iseq.PSet); !ok {
+		t.Error("PHashSet must implement PSet")
+	}
+
+	if _, ok := c.(iseq.Counted); !ok {
+		t.Error("PHashSet must implement Counted")
+	}
+
+	if _, ok := c.(iseq.Seqable); !ok {
+		t.Error("PHashSet must implement Seqable")
+	}
+
+	if _, ok := c.(iseq.Hashable); !ok {
+		t.Error("PHashSet must implement Hashable")
+	}
+}
+
+func TestPHashSetBasics(t *testing.T) {
+	s := NewPHashSetFromItems("a", "b", "a", nil, 3)
+	if s.Count() != 4 {
+		t.Errorf("Count: expected 4, got %v", s.Count())
+	}
+	for _, x := range []interface{}{"a", "b", nil, 3} {
+		if !s.Contains(x) {
+			t.Errorf("Contains(%v): expected true", x)
+		}
+	}
+	if s.Contains("c") {
+		t.Errorf("Contains(c): expected false")
+	}
+
+	if s.ConjS("a") != s {
+		t.Errorf("ConjS of an element already present should return the same set")
+	}
+	s2 := s.DisjoinS("a").DisjoinS(nil)
+	if s2.Count() != 2 || s2.Contains("a") || s2.Contains(nil) {
+		t.Errorf("DisjoinS: got %v elements", s2.Count())
+	}
+	if s.Count() != 4 {
+		t.Errorf("DisjoinS modified the original set")
+	}
+	if s.DisjoinS("zzz") != s {
+		t.Errorf("DisjoinS of a missing element should return the same set")
+	}
+
+	n := 0
+	for x := s.Seq(); x != nil; x = x.Next() {
+		if !s.Contains(x.First()) {
+			t.Errorf("Seq produced %v, not in the set", x.First())
+		}
+		n++
+	}
+	if n != 4 {
+		t.Errorf("Seq: expected 4 elements, got %v", n)
+	}
+	if EmptyPHashSet.Seq() != nil {
+		t.Errorf("Seq of empty set should be nil")
+	}
+}
+
+func TestPHashSetEquivAndHash(t *testing.T) {
+	s1 := NewPHashSetFromItems(1, 2, 3)
+	s2 := NewPHashSetFromSeq(NewPListFromSlice([]interface{}{3, 2, 1}))
+	if !s1.Equiv(s2) || !s2.Equiv(s1) {
+		t.Errorf("sets with the same elements should be Equiv")
+	}
+	if s1.Hash() != s2.Hash() {
+		t.Errorf("Equiv sets should have the same hash")
+	}
+	if s1.Equiv(NewPHashSetFromItems(1, 2)) || s1.Equiv(NewPHashSetFromItems(1, 2, 4)) {
+		t.Errorf("sets with different elements should not be Equiv")
+	}
+	if s1.Equiv(NewPVectorFromItems(1, 2, 3)) {
+		t.Errorf("a set should not be Equiv to a vector")
+	}
+}
+
+func TestPHashSetLarge(t *testing.T) {
+	s := EmptyPHashSet
+	for i := 0; i < 2000; i++ {
+		s = s.ConjS(i)
+	}
+	if s.Count() != 2000 {
+		t.Errorf("Count: expected 2000, got %v", s.Count())
+	}
+	seen := make(map[interface{}]bool)
+	for x := s.Seq(); x != nil; x = x.Next() {
+		seen[x.First()] = true
+	}
+	if len(seen) != 2000 {
+		t.Errorf("Seq: expected 2000 distinct elements, got %v", len(seen))
+	}
+	for i := 0; i < 2000; i += 2 {
+		s = s.DisjoinS(i)
+	}
+	if s.Count() != 1000 || s.Contains(0) || !s.Contains(1) {
+		t.Errorf("after DisjoinS: count %v", s.Count())
+	}
+}
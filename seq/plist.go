@@ -137,11 +137,7 @@ func (p *PList) Equiv(o interface{}) bool {
 		return true
 	}
 
-	if os, ok := o.(iseq.Seqable); ok {
-		return sequtil.SeqEquiv(p.Seq(), os.Seq())
-	}
-
-	return false
+	return sequtil.SequentialEquiv(p.Seq(), o)
 }
 
 func (p *PList) Hash() uint32 {
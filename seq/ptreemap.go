@@ -249,7 +249,19 @@ func (p *PTreeMap) Hash() uint32 {
 
 // tree operations
 
-func (m *PTreeMap) tmNodeAt(key interface{}) tmNode {
+// tmNodeAt returns the node for key, or nil if there is none.
+// A key the comparator can't compare to the map's keys (panicking with a *sequtil.CompareError)
+// is not in the map.
+func (m *PTreeMap) tmNodeAt(key interface{}) (node tmNode) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*sequtil.CompareError); !ok {
+				panic(r)
+			}
+			node = nil
+		}
+	}()
+
 	t := m.tree
 	for t != nil {
 		c := m.doCompare(key, t.key())
@@ -684,7 +696,7 @@ func (t *tmNodeSeq) Empty() iseq.PCollection {
 }
 
 func (t *tmNodeSeq) Equiv(o interface{}) bool {
-	return sequtil.Equiv(t, o)
+	return sequtil.SequentialEquiv(t, o)
 }
 
 // TODO: Test that keys are ordered when seq'd
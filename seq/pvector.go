@@ -5,7 +5,6 @@
 package seq
 
 import (
-	"errors"
 	"github.com/dmiller/go-seq/iseq"
 	"github.com/dmiller/go-seq/sequtil"
 )
@@ -136,7 +135,7 @@ func (v *PVector) NthE(i int) (interface{}, error) {
 	if i >= 0 && i < v.cnt {
 		return v.Nth(i), nil
 	}
-	return nil, errors.New("Index out of bounds in PVector")
+	return nil, &sequtil.IndexError{Op: "PVector.Nth", Index: i, Count: v.cnt}
 }
 
 // interface Lookup
@@ -167,10 +166,20 @@ func (v *PVector) EntryAt(key interface{}) iseq.MapEntry {
 }
 
 func (v *PVector) Assoc(key interface{}, val interface{}) iseq.Associative {
-	if i, ok := key.(int); ok {
-		return v.AssocN(i, val)
+	ret, err := v.AssocE(key, val)
+	if err != nil {
+		panic(err)
 	}
-	panic("Index must be an integer")
+	return ret
+}
+
+// AssocE is Assoc returning an error for a non-int key or an index out of range.
+func (v *PVector) AssocE(key interface{}, val interface{}) (iseq.Associative, error) {
+	i, ok := key.(int)
+	if !ok {
+		return nil, &sequtil.TypeError{Op: "PVector.Assoc", Value: key, Want: "an int index"}
+	}
+	return v.AssocNE(i, val)
 }
 
 // interface PVector
@@ -236,23 +245,32 @@ func newPath(level uint, node *vnode) *vnode {
 	return &ret
 }
 
-// AssocV returns a new vector with the i-th value set to the given value
+// AssocN returns a new vector with the i-th value set to the given value.
+// An index equal to the count adds the value at the end.
 func (v *PVector) AssocN(i int, val interface{}) iseq.PVector {
+	ret, err := v.AssocNE(i, val)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// AssocNE is AssocN returning an *sequtil.IndexError for an index out of range.
+func (v *PVector) AssocNE(i int, val interface{}) (iseq.PVector, error) {
 	if i >= 0 && i < v.cnt {
 		if i >= v.tailoff() {
 			newTail := make([]interface{}, len(v.tail))
 			copy(newTail, v.tail)
 			newTail[i&indexMask] = val
-			return &PVector{AMeta: AMeta{v.meta}, cnt: v.cnt, shift: v.shift, root: v.root, tail: newTail}
+			return &PVector{AMeta: AMeta{v.meta}, cnt: v.cnt, shift: v.shift, root: v.root, tail: newTail}, nil
 		}
-		return &PVector{AMeta: AMeta{v.meta}, cnt: v.cnt, shift: v.shift, root: doAssoc(v.shift, v.root, i, val), tail: v.tail}
+		return &PVector{AMeta: AMeta{v.meta}, cnt: v.cnt, shift: v.shift, root: doAssoc(v.shift, v.root, i, val), tail: v.tail}, nil
 
 	} else if i == v.cnt {
-		return v.ConsV(val)
+		return v.ConsV(val), nil
 	}
 
-	panic("Argument out of range")
-
+	return nil, &sequtil.IndexError{Op: "PVector.AssocN", Index: i, Count: v.cnt + 1}
 }
 
 func doAssoc(level uint, node *vnode, i int, val interface{}) *vnode {
@@ -277,10 +295,9 @@ func (v *PVector) Peek() interface{} {
 }
 
 func (v *PVector) Pop() iseq.PStack {
-	// TODO: convert to switch
 	if v.cnt == 0 {
 		// TODO: determine if pop should have other behavior
-		panic("Can't pop empty vector")
+		panic(&sequtil.EmptyError{Op: "PVector.Pop"})
 	}
 
 	if v.cnt == 1 {
@@ -313,6 +330,14 @@ func (v *PVector) Pop() iseq.PStack {
 	return &PVector{AMeta: AMeta{v.meta}, cnt: v.cnt - 1, shift: newShift, root: newRoot, tail: newTail}
 }
 
+// PopE is Pop returning an *sequtil.EmptyError for an empty vector.
+func (v *PVector) PopE() (iseq.PStack, error) {
+	if v.cnt == 0 {
+		return nil, &sequtil.EmptyError{Op: "PVector.Pop"}
+	}
+	return v.Pop(), nil
+}
+
 func (v *PVector) popTail(level uint, node *vnode) *vnode {
 	subidx := ((v.cnt - 2) >> level) & indexMask
 	if level > baseShift {
@@ -343,10 +368,9 @@ func (v *PVector) Rseq() iseq.Seq {
 // utilities
 
 func (v *PVector) arrayFor(i int) []interface{} {
-	if i < 0 && i >= v.cnt {
-		// TODO: create error objects for all panics
-		// THis is a panic in the same way as any array index out-of-bounds
-		panic("Array index out of bounds")
+	if i < 0 || i >= v.cnt {
+		// This is a panic in the same way as any array index out-of-bounds
+		panic(&sequtil.IndexError{Op: "PVector.Nth", Index: i, Count: v.cnt})
 	}
 
 	if i >= v.tailoff() {
@@ -388,6 +412,10 @@ func (p *PVector) Equiv(o interface{}) bool {
 		return true
 	}
 
+	if sequtil.IsGoSequential(o) {
+		return sequtil.SeqSliceEquiv(p.Seq(), o)
+	}
+
 	// TODO: when we have Sequential, fix this
 	if os, ok := o.(iseq.Seqable); ok {
 		s := os.Seq()
@@ -415,6 +443,36 @@ func (p *PVector) Hash() uint32 {
 	return p.hash
 }
 
+// interface Comparer
+
+// Compare orders vectors as Clojure does:  a shorter vector is less than a longer one;
+// vectors of the same length compare element-by-element with sequtil.DefaultCompareFn.
+// The argument must be an iseq.PVector or a Go slice or array; else panics.
+func (p *PVector) Compare(y interface{}) int {
+	if sequtil.IsGoSequential(y) {
+		return -sequtil.SliceCompare(y, p)
+	}
+
+	v, ok := y.(iseq.PVector)
+	if !ok {
+		panic(&sequtil.CompareError{X: p, Y: y})
+	}
+
+	n1, n2 := p.Count1(), v.Count1()
+	if n1 < n2 {
+		return -1
+	}
+	if n1 > n2 {
+		return 1
+	}
+	for i := 0; i < n1; i++ {
+		if c := sequtil.DefaultCompareFn(p.Nth(i), v.Nth(i)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
 /*
    static readonly AtomicReference<Thread> NoEdit = new AtomicReference<Thread>(null);
 
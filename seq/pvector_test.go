@@ -5,6 +5,7 @@
 package seq
 
 import (
+	"errors"
 	//"fmt"
 	"github.com/dmiller/go-seq/iseq"
 	"github.com/dmiller/go-seq/sequtil"
@@ -156,8 +157,11 @@ func TestPVectorFromItemsWorks(t *testing.T) {
 func TestPVectorEquiv(t *testing.T) {
 	sl := []interface{}{"def", 2, 3}
 	v := NewPVectorFromSlice(sl)
-	if v.Equiv(sl) {
-		t.Error("PVector should not Equals a non-PVector")
+	if !v.Equiv(sl) {
+		t.Error("PVector should equal a slice with equal elements")
+	}
+	if v.Equiv("def") {
+		t.Error("PVector should not Equals a non-sequential")
 	}
 
 	v1 := NewPVectorFromSlice(sl)
@@ -558,3 +562,87 @@ func TestPVectorPopOnEmpty(t *testing.T) {
 }
 
 // TODO: Add Rseq tests
+
+// Compare tests
+
+func TestPVectorCompare(t *testing.T) {
+	tests := []struct {
+		x, y interface{}
+		out  int
+	}{
+		{NewPVectorFromItems(1, 2), NewPVectorFromItems(1, 2), 0},
+		{NewPVectorFromItems(1, 2), NewPVectorFromItems(1, 3), -1},
+		{NewPVectorFromItems(9), NewPVectorFromItems(1, 2), -1},
+		{NewPVectorFromItems(1, 2, 3), EmptyPVector, 1},
+		{NewPVectorFromItems("b", NewPVectorFromItems(1)), NewPVectorFromItems("b", NewPVectorFromItems(0)), 1},
+		{NewPVectorFromItems(1, 2), []int{1, 3}, -1},
+		{[]int{1, 3}, NewPVectorFromItems(1, 2), 1},
+		{NewPListFromSlice([]interface{}{1, 2}), NewPListFromSlice([]interface{}{1, 2, 0}), -1},
+		{NewPListFromSlice([]interface{}{9}), NewPListFromSlice([]interface{}{1, 2}), 1},
+		{NewCons(1, CachedEmptyList), NewPListFromSlice([]interface{}{1}), 0},
+		{CachedEmptyList, NewPListFromSlice([]interface{}{1}), -1},
+		{NewPVectorFromItems(1, 2, 3).Seq(), NewPListFromSlice([]interface{}{1, 2, 4}), -1},
+	}
+	for i, tt := range tests {
+		if c := sequtil.DefaultCompareFn(tt.x, tt.y); c != tt.out {
+			t.Errorf("%d. DefaultCompareFn(%v, %v) => %d, want %d", i, tt.x, tt.y, c, tt.out)
+		}
+	}
+}
+
+func TestPTreeMapWithVectorKeys(t *testing.T) {
+	m := NewPTreeMapFromItems(
+		NewPVectorFromItems("2014-03-02", 1), "c",
+		NewPVectorFromItems("2014-03-01", 7), "b",
+		NewPVectorFromItems("2014-03-01", 2), "a")
+	want := []string{"a", "b", "c"}
+	i := 0
+	for s := m.Seq(); s != nil; s, i = s.Next(), i+1 {
+		if v := s.First().(iseq.MapEntry).Val(); v != want[i] {
+			t.Errorf("%d. entry value => %v, want %v", i, v, want[i])
+		}
+	}
+	if v := m.ValAt(NewPVectorFromItems("2014-03-01", 7)); v != "b" {
+		t.Errorf("ValAt([2014-03-01 7]) => %v, want b", v)
+	}
+}
+
+// Error-returning variants
+
+func TestPVectorErrorVariants(t *testing.T) {
+	v := NewPVectorFromItems(1, 2, 3)
+
+	var ie *sequtil.IndexError
+	if _, err := v.NthE(3); !errors.As(err, &ie) || ie.Index != 3 || ie.Count != 3 {
+		t.Errorf("NthE(3) => %v, want an IndexError", err)
+	}
+	if _, err := v.AssocNE(5, "x"); !errors.As(err, &ie) || ie.Index != 5 {
+		t.Errorf("AssocNE(5) => %v, want an IndexError", err)
+	}
+	if r, err := v.AssocNE(3, 4); err != nil || r.Count() != 4 {
+		t.Errorf("AssocNE(3) => %v, %v, want a 4-element vector", r, err)
+	}
+
+	var te *sequtil.TypeError
+	if _, err := v.AssocE("a", 1); !errors.As(err, &te) || te.Value != "a" {
+		t.Errorf("AssocE(a) => %v, want a TypeError", err)
+	}
+	if r, err := v.AssocE(0, 9); err != nil || r.(*PVector).Nth(0) != 9 {
+		t.Errorf("AssocE(0, 9) => %v, %v", r, err)
+	}
+
+	var ee *sequtil.EmptyError
+	if _, err := EmptyPVector.PopE(); !errors.As(err, &ee) {
+		t.Errorf("PopE on empty => %v, want an EmptyError", err)
+	}
+	if r, err := v.PopE(); err != nil || r.(*PVector).Count() != 2 {
+		t.Errorf("PopE => %v, %v", r, err)
+	}
+
+	defer func() {
+		if _, ok := recover().(*sequtil.IndexError); !ok {
+			t.Errorf("Nth(-1) should panic with an IndexError")
+		}
+	}()
+	v.Nth(-1)
+}
@@ -0,0 +1,112 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"github.com/dmiller/go-seq/murmur3"
+	"github.com/dmiller/go-seq/sequtil"
+	"strings"
+	"sync"
+)
+
+// Symbol is a name with an optional namespace, as in Clojure.
+//
+// Symbols are interned:  there is one *Symbol for each namespace/name pair,
+// so symbols can be compared with ==.
+type Symbol struct {
+	ns   string
+	name string
+	hash uint32
+}
+
+type symbolKey struct {
+	ns, name string
+}
+
+var symbols sync.Map // symbolKey -> *Symbol
+
+// InternSymbol returns the symbol for nsname.
+// A '/' separates the namespace from the name, as in "clojure.core/map";
+// "/" by itself is the symbol named "/".
+func InternSymbol(nsname string) *Symbol {
+	i := strings.IndexByte(nsname, '/')
+	if i <= 0 || nsname == "/" {
+		return InternSymbolNS("", nsname)
+	}
+	return InternSymbolNS(nsname[:i], nsname[i+1:])
+}
+
+// InternSymbolNS returns the symbol with the given namespace and name.
+// An empty ns means no namespace.
+func InternSymbolNS(ns string, name string) *Symbol {
+	key := symbolKey{ns, name}
+	if s, ok := symbols.Load(key); ok {
+		return s.(*Symbol)
+	}
+	s, _ := symbols.LoadOrStore(key, newSymbol(ns, name))
+	return s.(*Symbol)
+}
+
+func newSymbol(ns string, name string) *Symbol {
+	// Symbol.hasheq:  the ns is hashed with String.hashCode, a missing ns as 0
+	var nsHash uint32
+	if ns != "" {
+		nsHash = uint32(murmur3.StringHashCode(ns))
+	}
+	return &Symbol{ns: ns, name: name, hash: sequtil.HashCombine(murmur3.HashUnencodedChars(name), nsHash)}
+}
+
+// Namespace returns the namespace of the symbol, "" if none.
+func (s *Symbol) Namespace() string {
+	return s.ns
+}
+
+// Name returns the name of the symbol.
+func (s *Symbol) Name() string {
+	return s.name
+}
+
+func (s *Symbol) String() string {
+	if s.ns == "" {
+		return s.name
+	}
+	return s.ns + "/" + s.name
+}
+
+// interfaces Equivable, Hashable, Comparer
+
+// Equiv returns true if o is the same symbol.
+func (s *Symbol) Equiv(o interface{}) bool {
+	s2, ok := o.(*Symbol)
+	return ok && s == s2
+}
+
+// Hash matches Clojure's hasheq for symbols.
+func (s *Symbol) Hash() uint32 {
+	return s.hash
+}
+
+// Compare orders symbols as Clojure does:  symbols without a namespace first,
+// then by namespace, then by name.
+// Panics with a *sequtil.CompareError if y is not a *Symbol.
+func (s *Symbol) Compare(y interface{}) int {
+	s2, ok := y.(*Symbol)
+	if !ok {
+		panic(&sequtil.CompareError{X: s, Y: y})
+	}
+	if s == s2 {
+		return 0
+	}
+	switch {
+	case s.ns == "" && s2.ns != "":
+		return -1
+	case s.ns != "" && s2.ns == "":
+		return 1
+	}
+	if c := strings.Compare(s.ns, s2.ns); c != 0 {
+		return c
+	}
+	return strings.Compare(s.name, s2.name)
+}
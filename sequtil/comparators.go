@@ -0,0 +1,89 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+)
+
+// SeqCompare compares two sequences lexicographically, element-by-element with DefaultCompareFn.
+// If one sequence is a prefix of the other, the shorter is less.
+func SeqCompare(s1 iseq.Seq, s2 iseq.Seq) int {
+	for ; s1 != nil && s2 != nil; s1, s2 = s1.Next(), s2.Next() {
+		if c := DefaultCompareFn(s1.First(), s2.First()); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case s1 == nil && s2 == nil:
+		return 0
+	case s1 == nil:
+		return -1
+	}
+	return 1
+}
+
+// isSeqLike returns true for lists and seqs, which DefaultCompareFn orders with SeqCompare.
+func isSeqLike(o interface{}) bool {
+	switch o.(type) {
+	case iseq.Seq, iseq.PList:
+		return true
+	}
+	return false
+}
+
+// Reverse returns a comparison function giving the reverse of the ordering of c.
+func Reverse(c iseq.CompareFn) iseq.CompareFn {
+	return func(x interface{}, y interface{}) int {
+		return c(y, x)
+	}
+}
+
+// By returns a comparison function ordering values by DefaultCompareFn applied to keyFn of each.
+func By(keyFn func(interface{}) interface{}) iseq.CompareFn {
+	return func(x interface{}, y interface{}) int {
+		return DefaultCompareFn(keyFn(x), keyFn(y))
+	}
+}
+
+// ThenBy returns a comparison function that orders by c, breaking ties with next.
+func ThenBy(c iseq.CompareFn, next iseq.CompareFn) iseq.CompareFn {
+	return func(x interface{}, y interface{}) int {
+		if r := c(x, y); r != 0 {
+			return r
+		}
+		return next(x, y)
+	}
+}
+
+// NilsFirst returns a comparison function ordering nil before everything else, and non-nils by c.
+func NilsFirst(c iseq.CompareFn) iseq.CompareFn {
+	return func(x interface{}, y interface{}) int {
+		switch {
+		case x == nil && y == nil:
+			return 0
+		case x == nil:
+			return -1
+		case y == nil:
+			return 1
+		}
+		return c(x, y)
+	}
+}
+
+// NilsLast returns a comparison function ordering nil after everything else, and non-nils by c.
+func NilsLast(c iseq.CompareFn) iseq.CompareFn {
+	return func(x interface{}, y interface{}) int {
+		switch {
+		case x == nil && y == nil:
+			return 0
+		case x == nil:
+			return 1
+		case y == nil:
+			return -1
+		}
+		return c(x, y)
+	}
+}
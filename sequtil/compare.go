@@ -6,13 +6,17 @@ package sequtil
 
 import (
 	"github.com/dmiller/go-seq/iseq"
+	"math"
+	"math/big"
 	"reflect"
 )
 
 // DefaultCompareFn is a default function to use for comparisons.
-// Handles identity, nils, strings, numerics, and things implementing the iseq.Comparer interface.
+// Handles identity, nils, strings, numerics, things implementing the iseq.Comparer interface,
+// lists and seqs (ordered lexicographically, see SeqCompare),
+// Go slices and arrays (ordered as vectors), and registered structs.
 func DefaultCompareFn(k1 interface{}, k2 interface{}) int {
-	if k1 == k2 {
+	if identical(k1, k2) {
 		return 0
 	}
 	if k1 != nil {
@@ -31,20 +35,47 @@ func DefaultCompareFn(k1 interface{}, k2 interface{}) int {
 		if IsComparableNumeric(k1) {
 			return CompareComparableNumeric(k1, k2)
 		}
-		panic("Can't compare")
+		if isSeqLike(k1) && isSeqLike(k2) {
+			return SeqCompare(k1.(iseq.Seqable).Seq(), k2.(iseq.Seqable).Seq())
+		}
+		if IsGoSequential(k1) {
+			return SliceCompare(k1, k2)
+		}
+		if isRegisteredStruct(k1) {
+			return compareStruct(k1, k2)
+		}
+		panic(&CompareError{k1, k2})
 	}
 	return -1
 }
 
+// DefaultCompareFnE is DefaultCompareFn returning a *CompareError for values that cannot be compared,
+// including elements of collections being compared.
+// Panics from iseq.Comparer implementations other than *CompareError are not recovered.
+func DefaultCompareFnE(k1 interface{}, k2 interface{}) (c int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ce, ok := r.(*CompareError)
+			if !ok {
+				panic(r)
+			}
+			err = ce
+		}
+	}()
+	return DefaultCompareFn(k1, k2), nil
+}
+
 // IsComparableNumeric checks a value to see if it a numeric value amenable to comparison
 func IsComparableNumeric(v interface{}) bool {
 
 	switch v.(type) {
-	case bool, int, int8, int32, int64,
-		uint, uint8, uint32, uint64,
+	case bool, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, uintptr,
 		float32, float64:
 
 		return true
+	case *big.Int, *big.Rat, *big.Float:
+		return IsNumeric(v)
 	}
 	return false
 }
@@ -61,33 +92,42 @@ func CompareString(s string, x interface{}) int {
 		return 1
 	}
 
-	panic("can't compare string to non-string")
+	panic(&CompareError{s, x})
 }
 
-// CompareComparableNumeric compares two values, assumed to comparable numerics
+// CompareComparableNumeric compares two values, assumed to comparable numerics.
+// Values are compared exactly across integer, ratio, and float types:  1/3 < 0.34 < 1.
 func CompareComparableNumeric(x1 interface{}, x2 interface{}) int {
+	if isBig(x1) || isBig(x2) {
+		return compareNumericBig(x1, x2)
+	}
 	// x1 should be numeric
 	switch x1 := x1.(type) {
 	case bool:
 		b1 := bool(x1)
 		if b1 {
-			return compareNumericInt(int64(1), x2)
+			return compareNumericInt(x1, int64(1), x2)
 		}
-		return compareNumericInt(int64(0), x2)
-	case int, int8, int32, int64:
+		return compareNumericInt(x1, int64(0), x2)
+	case int, int8, int16, int32, int64:
 		n1 := reflect.ValueOf(x1).Int()
-		return compareNumericInt(n1, x2)
-	case uint, uint8, uint32, uint64:
+		return compareNumericInt(x1, n1, x2)
+	case uint, uint8, uint16, uint32, uint64, uintptr:
 		n1 := reflect.ValueOf(x1).Uint()
-		return compareNumericUint(n1, x2)
+		return compareNumericUint(x1, n1, x2)
 	case float32, float64:
 		n1 := reflect.ValueOf(x1).Float()
-		return compareNumericFloat(n1, x2)
+		return compareNumericFloat(x1, n1, x2)
 	}
-	panic("Expect first arg to be numeric")
+	panic(&CompareError{x1, x2})
 }
 
-func compareNumericInt(n1 int64, x2 interface{}) int {
+// Integers of at most this magnitude convert to float64 exactly;
+// larger ones are compared to floats as *big.Rats.
+const maxExactFloat = 1 << 53
+
+// compareNumericInt compares x1, with integer value n1, to x2.
+func compareNumericInt(x1 interface{}, n1 int64, x2 interface{}) int {
 	switch x2 := x2.(type) {
 	case bool:
 		b2 := bool(x2)
@@ -103,7 +143,7 @@ func compareNumericInt(n1 int64, x2 interface{}) int {
 		}
 		return 0
 
-	case int, int8, int32, int64:
+	case int, int8, int16, int32, int64:
 		n2 := reflect.ValueOf(x2).Int()
 		if n1 < n2 {
 			return -1
@@ -113,12 +153,12 @@ func compareNumericInt(n1 int64, x2 interface{}) int {
 		}
 		return 0
 
-	case uint, uint8, uint32, uint64:
+	case uint, uint8, uint16, uint32, uint64, uintptr:
 		n2 := reflect.ValueOf(x2).Uint()
 		if n1 < 0 {
 			return -1
 		}
-		un1 := uint64(n2)
+		un1 := uint64(n1)
 		if un1 < n2 {
 			return -1
 		}
@@ -128,6 +168,9 @@ func compareNumericInt(n1 int64, x2 interface{}) int {
 		return 0
 
 	case float32, float64:
+		if n1 < -maxExactFloat || n1 > maxExactFloat {
+			return compareNumericBig(x1, x2)
+		}
 		n2 := reflect.ValueOf(x2).Float()
 		fn1 := float64(n1)
 		if fn1 < n2 {
@@ -138,10 +181,11 @@ func compareNumericInt(n1 int64, x2 interface{}) int {
 		}
 		return 0
 	}
-	return -1 // what else, other than panic?
+	panic(&CompareError{x1, x2})
 }
 
-func compareNumericUint(n1 uint64, x2 interface{}) int {
+// compareNumericUint compares x1, with unsigned integer value n1, to x2.
+func compareNumericUint(x1 interface{}, n1 uint64, x2 interface{}) int {
 	switch x2 := x2.(type) {
 	case bool:
 		b2 := bool(x2)
@@ -157,7 +201,7 @@ func compareNumericUint(n1 uint64, x2 interface{}) int {
 		}
 		return 0
 
-	case int, int8, int32, int64:
+	case int, int8, int16, int32, int64:
 		n2 := reflect.ValueOf(x2).Int()
 		if n2 < 0 {
 			return 1
@@ -171,7 +215,7 @@ func compareNumericUint(n1 uint64, x2 interface{}) int {
 		}
 		return 0
 
-	case uint, uint8, uint32, uint64:
+	case uint, uint8, uint16, uint32, uint64, uintptr:
 		n2 := reflect.ValueOf(x2).Uint()
 		if n1 < n2 {
 			return -1
@@ -182,6 +226,9 @@ func compareNumericUint(n1 uint64, x2 interface{}) int {
 		return 0
 
 	case float32, float64:
+		if n1 > maxExactFloat {
+			return compareNumericBig(x1, x2)
+		}
 		n2 := reflect.ValueOf(x2).Float()
 		fn1 := float64(n1)
 		if fn1 < n2 {
@@ -192,20 +239,33 @@ func compareNumericUint(n1 uint64, x2 interface{}) int {
 		}
 		return 0
 	}
-	return -1 // what else, other than panic?
+	panic(&CompareError{x1, x2})
 }
 
-func compareNumericFloat(n1 float64, x2 interface{}) int {
+// compareNumericFloat compares x1, with floating-point value n1, to x2.
+func compareNumericFloat(x1 interface{}, n1 float64, x2 interface{}) int {
 	var n2 float64
 	switch x2 := x2.(type) {
-	case bool, int, int8, int32, int64:
-		n2 = float64(reflect.ValueOf(x2).Int())
-	case uint, uint8, uint32, uint64:
-		n2 = float64(reflect.ValueOf(x2).Uint())
+	case bool:
+		if x2 {
+			n2 = 1
+		}
+	case int, int8, int16, int32, int64:
+		i2 := reflect.ValueOf(x2).Int()
+		if i2 < -maxExactFloat || i2 > maxExactFloat {
+			return compareNumericBig(x1, x2)
+		}
+		n2 = float64(i2)
+	case uint, uint8, uint16, uint32, uint64, uintptr:
+		u2 := reflect.ValueOf(x2).Uint()
+		if u2 > maxExactFloat {
+			return compareNumericBig(x1, x2)
+		}
+		n2 = float64(u2)
 	case float32, float64:
 		n2 = reflect.ValueOf(x2).Float()
 	default:
-		return -1 // what else, other than panic?
+		panic(&CompareError{x1, x2})
 	}
 	if n1 < n2 {
 		return -1
@@ -215,3 +275,60 @@ func compareNumericFloat(n1 float64, x2 interface{}) int {
 	}
 	return 0
 }
+
+// compareNumericBig compares two numerics, at least one of them a *big.Int, *big.Rat, or *big.Float.
+// Finite values are compared as *big.Rats.
+func compareNumericBig(x1 interface{}, x2 interface{}) int {
+	if !IsComparableNumeric(x1) || !IsComparableNumeric(x2) {
+		panic(&CompareError{x1, x2})
+	}
+	r1, inf1 := toBigRat(x1)
+	r2, inf2 := toBigRat(x2)
+	if r1 == nil && inf1 == 0 || r2 == nil && inf2 == 0 {
+		return 0 // NaN is neither less nor greater
+	}
+	if inf1 != 0 || inf2 != 0 {
+		switch {
+		case inf1 < inf2:
+			return -1
+		case inf1 > inf2:
+			return 1
+		}
+		return 0
+	}
+	return r1.Cmp(r2)
+}
+
+// toBigRat converts a comparable numeric to a *big.Rat.
+// Infinities return a nil *big.Rat and the sign; NaN returns nil and 0.
+func toBigRat(v interface{}) (*big.Rat, int) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return big.NewRat(1, 1), 0
+		}
+		return new(big.Rat), 0
+	case *big.Rat:
+		return v, 0
+	case *big.Int:
+		return new(big.Rat).SetInt(v), 0
+	case *big.Float:
+		if v.IsInf() {
+			return nil, v.Sign()
+		}
+		r, _ := v.Rat(nil)
+		return r, 0
+	case float32, float64:
+		f := reflect.ValueOf(v).Float()
+		switch {
+		case math.IsNaN(f):
+			return nil, 0
+		case math.IsInf(f, 1):
+			return nil, 1
+		case math.IsInf(f, -1):
+			return nil, -1
+		}
+		return new(big.Rat).SetFloat64(f), 0
+	}
+	return new(big.Rat).SetInt(toBigInt(v)), 0
+}
@@ -0,0 +1,95 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+var compareNumericTests = []struct {
+	x, y interface{}
+	out  int
+}{
+	{1, 2, -1},
+	{int16(3), uint16(3), 0},
+	{uintptr(4), int8(3), 1},
+	{int64(5), uint64(7), -1},
+	{int64(7), uint64(5), 1},
+	{int64(-1), uint64(math.MaxUint64), -1},
+	{uint64(math.MaxUint64), int64(-1), 1},
+	{int64(1 << 53), float64(1 << 53), 0},
+	{int64(1<<53 + 1), float64(1 << 53), 1},
+	{float64(1 << 53), int64(1<<53 + 1), -1},
+	{int64(-1<<53 - 1), float64(-1 << 53), -1},
+	{uint64(1<<53 + 1), float64(1 << 53), 1},
+	{float64(1 << 53), uint64(1<<53 + 1), -1},
+	{int64(math.MaxInt64), float64(1 << 63), -1},
+	{uint64(1 << 63), float64(1 << 63), 0},
+	{uint64(math.MaxUint64), float64(1 << 64), -1},
+	{float64(1 << 64), uint64(math.MaxUint64), 1},
+	{true, float64(1), 0},
+	{1.5, true, 1},
+	{big.NewInt(5), 5, 0},
+	{5, big.NewInt(6), -1},
+	{bigInt("18446744073709551616"), uint64(math.MaxUint64), 1},
+	{bigInt("-18446744073709551616"), int64(math.MinInt64), -1},
+	{big.NewRat(1, 3), 0.34, -1},
+	{0.33, big.NewRat(1, 3), -1},
+	{big.NewRat(1, 2), 0.5, 0},
+	{big.NewRat(1, 2), big.NewRat(2, 3), -1},
+	{big.NewRat(3, 2), 1, 1},
+	{big.NewFloat(2.5), big.NewRat(5, 2), 0},
+	{big.NewFloat(2.5), 3, -1},
+	{big.NewInt(1000), math.Inf(1), -1},
+	{math.Inf(-1), big.NewRat(-1000, 1), -1},
+	{new(big.Float).SetInf(false), math.Inf(1), 0},
+	{false, big.NewInt(0), 0},
+}
+
+func TestCompareComparableNumeric(t *testing.T) {
+	for i, tt := range compareNumericTests {
+		if !IsComparableNumeric(tt.x) || !IsComparableNumeric(tt.y) {
+			t.Errorf("%d. IsComparableNumeric(%v, %v) => false, want true", i, tt.x, tt.y)
+			continue
+		}
+		if c := CompareComparableNumeric(tt.x, tt.y); c != tt.out {
+			t.Errorf("%d. CompareComparableNumeric(%v, %v) => %d, want %d", i, tt.x, tt.y, c, tt.out)
+		}
+		if c := DefaultCompareFn(tt.x, tt.y); c != tt.out {
+			t.Errorf("%d. DefaultCompareFn(%v, %v) => %d, want %d", i, tt.x, tt.y, c, tt.out)
+		}
+	}
+}
+
+func TestComparators(t *testing.T) {
+	byLen := By(func(x interface{}) interface{} { return len(x.(string)) })
+	tests := []struct {
+		name string
+		c    func(interface{}, interface{}) int
+		x, y interface{}
+		out  int
+	}{
+		{"Reverse", Reverse(DefaultCompareFn), 1, 2, 1},
+		{"Reverse", Reverse(DefaultCompareFn), "b", "a", -1},
+		{"By", byLen, "bb", "a", 1},
+		{"By", byLen, "b", "a", 0},
+		{"ThenBy", ThenBy(byLen, DefaultCompareFn), "b", "a", 1},
+		{"ThenBy", ThenBy(byLen, DefaultCompareFn), "b", "aa", -1},
+		{"ThenBy", ThenBy(byLen, Reverse(DefaultCompareFn)), "a", "b", 1},
+		{"NilsFirst", NilsFirst(DefaultCompareFn), nil, 1, -1},
+		{"NilsFirst", NilsFirst(Reverse(DefaultCompareFn)), 1, nil, 1},
+		{"NilsFirst", NilsFirst(DefaultCompareFn), nil, nil, 0},
+		{"NilsLast", NilsLast(DefaultCompareFn), nil, 1, 1},
+		{"NilsLast", NilsLast(DefaultCompareFn), 1, nil, -1},
+		{"NilsLast", NilsLast(Reverse(DefaultCompareFn)), 1, 2, 1},
+	}
+	for i, tt := range tests {
+		if c := tt.c(tt.x, tt.y); c != tt.out {
+			t.Errorf("%d. %s(%v, %v) => %d, want %d", i, tt.name, tt.x, tt.y, c, tt.out)
+		}
+	}
+}
@@ -6,17 +6,30 @@ package sequtil
 
 import (
 	"github.com/dmiller/go-seq/iseq"
+	"reflect"
+	"unicode/utf8"
 )
 
 // Count computes the length of a sequence.
-// Handles nil, iseq.Counted, iseq.PCollection, and strings
+// Handles nil, iseq.Counted, iseq.PCollection, strings (counting runes, as their seqs do), and Go slices, arrays, and maps.
+// (Channels are not counted:  counting would consume them.)
+// Panics on other types.  See CountE.
 func Count(o interface{}) int {
+	n, err := CountE(o)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// CountE is Count returning a *TypeError for unsupported types.
+func CountE(o interface{}) (int, error) {
 	if o == nil {
-		return 0
+		return 0, nil
 	}
 
 	if cnt, ok := o.(iseq.Counted); ok {
-		return cnt.Count1()
+		return cnt.Count1(), nil
 	}
 
 	if pc, ok := o.(iseq.PCollection); ok {
@@ -24,18 +37,24 @@ func Count(o interface{}) int {
 		i := 0
 		for ; s != nil; s = s.Next() {
 			if c, ok := s.(iseq.Counted); ok {
-				return i + c.Count1()
+				return i + c.Count1(), nil
 			}
 			i++
 		}
-		return i
+		return i, nil
 	}
 
 	if s, ok := o.(string); ok {
-		return len(s)
+		return utf8.RuneCountInString(s), nil
+	}
+
+	switch v := reflect.ValueOf(o); v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), nil
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), nil
 	}
-	// TODO: Figure out how to  handle arrays, slices, maps in a typeswitch/generic way
-	panic("Count not supported on this type")
+	return 0, &TypeError{"Count", o, "countable"}
 }
 
 // SeqCount computes the length of an iseq.Seq
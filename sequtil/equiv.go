@@ -11,9 +11,12 @@ import (
 // Equiv returns true if the objects are 'equivalent'.
 // Two == objects are equivalent.
 // Else, if either is iseq.Equivable, we default to that interface.
+// Numbers are equivalent if they are in the same category (integer, float, complex)
+// and have the same value, as with Clojure's =.  See NumEquiv.
+// Go slices, arrays, maps, and registered structs are compared structurally.  See RegisterStruct.
 // Otherwise, not equivalent.
 func Equiv(o1 interface{}, o2 interface{}) bool {
-	if o1 == o2 {
+	if identical(o1, o2) {
 		return true
 	}
 
@@ -30,21 +33,24 @@ func Equiv(o1 interface{}, o2 interface{}) bool {
 		return e2.Equiv(o1)
 	}
 
-	return false
+	if IsNumeric(o1) {
+		return NumEquiv(o1, o2)
+	}
+
+	return reflectEquiv(o1, o2)
 }
 
 // MapEquiv returns true if its arguments are equivalent as maps.
 // First argument is an iseq.PMap.
-// Second argument must be convertible to a map. (Someeday we may handle go maps.)
+// Second argument must be an iseq.PMap or a Go map.
 // To be equivalent, must contain equivalent keys and values.
 func MapEquiv(m1 iseq.PMap, obj interface{}) bool {
-	if m1 == obj {
+	if identical(m1, obj) {
 		return true
 	}
 
-	if _, ok := obj.(map[interface{}]interface{}); ok {
-		// TODO: figure out how to handle go maps
-		return false
+	if IsGoMap(obj) {
+		return goMapEquiv(m1, obj)
 	}
 
 	if m2, ok := obj.(iseq.PMap); ok {
@@ -64,6 +70,18 @@ func MapEquiv(m1 iseq.PMap, obj interface{}) bool {
 	return false
 }
 
+// SequentialEquiv returns true if the sequence is element-by-element equivalent
+// to the second argument, an iseq.Seqable or a Go slice or array.
+func SequentialEquiv(s iseq.Seq, o interface{}) bool {
+	if os, ok := o.(iseq.Seqable); ok {
+		return SeqEquiv(s, os.Seq())
+	}
+	if IsGoSequential(o) {
+		return SeqSliceEquiv(s, o)
+	}
+	return false
+}
+
 // SeqEquiv returns true if the sequences are element-by-element equivalent.
 func SeqEquiv(s1 iseq.Seq, s2 iseq.Seq) bool {
 	if s1 == s2 {
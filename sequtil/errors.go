@@ -0,0 +1,63 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"fmt"
+)
+
+// Errors returned by the E-suffixed functions and methods (NthE, HashE, AssocE, ...).
+// The panicking versions panic with the same values, so a recover can inspect them too.
+
+// IndexError reports an index out of range.
+type IndexError struct {
+	Op    string // the operation, such as "PVector.Nth"
+	Index int
+	Count int
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("%s: index %d out of range [0, %d)", e.Op, e.Index, e.Count)
+}
+
+// TypeError reports a value of a type the operation does not support.
+type TypeError struct {
+	Op    string
+	Value interface{}
+	Want  string // a description of what is supported
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s: %v (%T) is not %s", e.Op, e.Value, e.Value, e.Want)
+}
+
+// EmptyError reports an operation requiring a non-empty collection.
+type EmptyError struct {
+	Op string
+}
+
+func (e *EmptyError) Error() string {
+	return e.Op + ": collection is empty"
+}
+
+// ArgumentError reports a bad argument value.
+type ArgumentError struct {
+	Op     string
+	Value  interface{}
+	Reason string
+}
+
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Op, e.Reason, e.Value)
+}
+
+// CompareError reports values that cannot be compared.
+type CompareError struct {
+	X, Y interface{}
+}
+
+func (e *CompareError) Error() string {
+	return fmt.Sprintf("can't compare %v (%T) to %v (%T)", e.X, e.X, e.Y, e.Y)
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorReturningVariants(t *testing.T) {
+	if _, err := CountE(42); !isTypeError(err, 42) {
+		t.Errorf("CountE(42) => %v, want a *TypeError", err)
+	}
+	if n, err := CountE("abc"); n != 3 || err != nil {
+		t.Errorf("CountE(abc) => %d, %v, want 3, nil", n, err)
+	}
+	if _, err := ConvertToSeqE(3.5); !isTypeError(err, 3.5) {
+		t.Errorf("ConvertToSeqE(3.5) => %v, want a *TypeError", err)
+	}
+	if s, err := ConvertToSeqE(nil); s != nil || err != nil {
+		t.Errorf("ConvertToSeqE(nil) => %v, %v, want nil, nil", s, err)
+	}
+	ch := make(chan int)
+	if _, err := HashE(ch); !isTypeError(err, ch) {
+		t.Errorf("HashE(chan) => %v, want a *TypeError", err)
+	}
+
+	var ce *CompareError
+	if _, err := DefaultCompareFnE("a", 1); !errors.As(err, &ce) || ce.X != "a" || ce.Y != 1 {
+		t.Errorf("DefaultCompareFnE(a, 1) => %v, want a *CompareError", err)
+	}
+	if _, err := DefaultCompareFnE([]interface{}{1, "x"}, []interface{}{1, struct{}{}}); !errors.As(err, &ce) || ce.X != "x" {
+		t.Errorf("DefaultCompareFnE on slices with incomparable elements => %v, want a *CompareError on the elements", err)
+	}
+	for i, x := range []interface{}{1, uint8(1), 1.5, true} {
+		if _, err := DefaultCompareFnE(x, "x"); !errors.As(err, &ce) || ce.X != x || ce.Y != "x" {
+			t.Errorf("%d. DefaultCompareFnE(%v, x) => %v, want a *CompareError", i, x, err)
+		}
+	}
+	if c, err := DefaultCompareFnE("a", "b"); c != -1 || err != nil {
+		t.Errorf("DefaultCompareFnE(a, b) => %d, %v, want -1, nil", c, err)
+	}
+
+	defer func() {
+		if _, ok := recover().(*TypeError); !ok {
+			t.Errorf("Count(42) should panic with a *TypeError")
+		}
+	}()
+	Count(42)
+}
+
+func TestErrorVariantsOnUncomparableValues(t *testing.T) {
+	// The types of these are comparable, but == panics on them
+	a := [2]interface{}{1, []int{1}}
+	b := boxed{"b", []int{1}}
+
+	var ce *CompareError
+	if c, err := DefaultCompareFnE(a, [2]interface{}{1, []int{1}}); c != 0 || err != nil {
+		t.Errorf("DefaultCompareFnE(%v, same) => %d, %v, want 0, nil", a, c, err)
+	}
+	if _, err := DefaultCompareFnE(a, [2]interface{}{1, map[int]int{}}); !errors.As(err, &ce) {
+		t.Errorf("DefaultCompareFnE of a slice element to a map element => %v, want a *CompareError", err)
+	}
+	if c, err := DefaultCompareFnE(b, boxed{"b", []int{1}}); c != 0 || err != nil {
+		t.Errorf("DefaultCompareFnE(%v, same) => %d, %v, want 0, nil", b, c, err)
+	}
+	if _, err := DefaultCompareFnE(b, boxed{"b", map[int]int{}}); !errors.As(err, &ce) {
+		t.Errorf("DefaultCompareFnE of structs with incomparable fields => %v, want a *CompareError", err)
+	}
+	if _, err := DefaultCompareFnE(b, a); !errors.As(err, &ce) {
+		t.Errorf("DefaultCompareFnE(struct, array) => %v, want a *CompareError", err)
+	}
+
+	if n, err := CountE(a); n != 2 || err != nil {
+		t.Errorf("CountE(%v) => %d, %v, want 2, nil", a, n, err)
+	}
+	var te *TypeError
+	for i, v := range []interface{}{b, unregistered{[]int{1}}, struct{ X interface{} }{[]int{1}}} {
+		if _, err := CountE(v); !errors.As(err, &te) {
+			t.Errorf("%d. CountE(%v) => %v, want a *TypeError", i, v, err)
+		}
+		if _, err := ConvertToSeqE(v); !errors.As(err, &te) {
+			t.Errorf("%d. ConvertToSeqE(%v) => %v, want a *TypeError", i, v, err)
+		}
+	}
+}
+
+func isTypeError(err error, v interface{}) bool {
+	var te *TypeError
+	return errors.As(err, &te) && Equiv(te.Value, v)
+}
+
+func TestErrorMessages(t *testing.T) {
+	tests := []struct {
+		err error
+		out string
+	}{
+		{&IndexError{"PVector.Nth", 5, 3}, "PVector.Nth: index 5 out of range [0, 3)"},
+		{&TypeError{"PVector.Assoc", "a", "an int index"}, "PVector.Assoc: a (string) is not an int index"},
+		{&EmptyError{"PVector.Pop"}, "PVector.Pop: collection is empty"},
+		{&ArgumentError{"NewPHashMap", "k", "no value supplied for key"}, "NewPHashMap: no value supplied for key: k"},
+		{&CompareError{1, "a"}, "can't compare 1 (int) to a (string)"},
+	}
+	for i, tt := range tests {
+		if s := tt.err.Error(); s != tt.out {
+			t.Errorf("%d. Error() => %q, want %q", i, s, tt.out)
+		}
+	}
+}
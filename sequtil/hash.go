@@ -5,11 +5,11 @@
 package sequtil
 
 import (
-	"errors"
 	"github.com/dmiller/go-seq/iseq"
 	"github.com/dmiller/go-seq/murmur3"
 	//"fmt"
 	"math"
+	"math/big"
 	"reflect"
 )
 
@@ -21,6 +21,20 @@ import (
 // But it is one way not to have an error code and to avoid a panic.
 // Use IsHashable to determine if Hash is supported.
 // Or call HashE which has an error return.
+//
+// Hash codes match Clojure's hasheq (as of Clojure 1.6), so equal values hash the same here and on the JVM:
+//
+//	nil                     0
+//	bool                    Boolean.hashCode: 1231 for true, 1237 for false
+//	integers                Murmur3.hashLong of the value (uint64s above MaxInt64 hash as a BigInt)
+//	*big.Int                as a long if it fits, otherwise BigInteger.hashCode
+//	*big.Rat                as an integer if it is one, otherwise Ratio.hashCode
+//	float32, float64        Double.hashCode of the value (as a float64), with -0.0 hashing as 0.0
+//	*big.Float              as the nearest float64
+//	string                  Murmur3.hashInt of String.hashCode
+//	collections             Murmur3.hashOrdered / hashUnordered, via their Hash methods
+//	Go slices and arrays    as vectors
+//	Go maps                 as maps
 func Hash(v interface{}) uint32 {
 	h, err := HashE(v)
 	if err != nil {
@@ -39,20 +53,82 @@ func HashE(v interface{}) (uint32, error) {
 	}
 
 	switch v := v.(type) {
-	case bool, int, int8, int32, int64:
-		return murmur3.HashUInt64(uint64(reflect.ValueOf(v).Int())), nil
-	case uint, uint8, uint32, uint64:
-		return murmur3.HashUInt64(uint64(reflect.ValueOf(v).Uint())), nil
+	case bool:
+		return HashBool(v), nil
+	case int, int8, int16, int32, int64:
+		return murmur3.HashInt64(reflect.ValueOf(v).Int()), nil
+	case uint, uint8, uint16, uint32, uint64, uintptr:
+		return HashUint64(reflect.ValueOf(v).Uint()), nil
+	case *big.Int:
+		if v != nil {
+			return HashBigInt(v), nil
+		}
+	case *big.Rat:
+		if v != nil {
+			return HashBigRat(v), nil
+		}
+	case *big.Float:
+		if v != nil {
+			return HashBigFloat(v), nil
+		}
 	case float32, float64:
-		return murmur3.HashUInt64(math.Float64bits(reflect.ValueOf(v).Float())), nil
+		return HashFloat64(reflect.ValueOf(v).Float()), nil
 	case nil:
-		return murmur3.HashUInt64(0), nil
+		return 0, nil
 	case string:
-		return murmur3.HashString(v), nil
-	case complex64, complex128:
-		return HashComplex128(v.(complex128)), nil
+		return HashString(v), nil
+	case complex64:
+		return HashComplex128(complex128(v)), nil
+	case complex128:
+		return HashComplex128(v), nil
 	}
-	return 0, errors.New("don't know how to hash")
+	return hashReflect(v)
+}
+
+// HashBool computes a hash for a bool, as Java's Boolean.hashCode.
+func HashBool(b bool) uint32 {
+	if b {
+		return 1231
+	}
+	return 1237
+}
+
+// HashUint64 computes a hash for a uint64.
+// Values that fit in an int64 hash as Clojure longs; larger values hash as Clojure BigInts.
+func HashUint64(u uint64) uint32 {
+	if u <= math.MaxInt64 {
+		return murmur3.HashUInt64(u)
+	}
+	// BigInteger.hashCode over the magnitude's 32-bit words, most significant first
+	return 31*uint32(u>>32) + uint32(u)
+}
+
+// HashFloat64 computes a hash for a float64, as Java's Double.hashCode, except that -0.0 hashes as 0.0.
+func HashFloat64(f float64) uint32 {
+	if f == 0 {
+		return 0
+	}
+	var bits uint64
+	if math.IsNaN(f) {
+		// doubleToLongBits canonicalizes NaNs
+		bits = 0x7ff8000000000000
+	} else {
+		bits = math.Float64bits(f)
+	}
+	return uint32(bits ^ bits>>32)
+}
+
+// HashString computes a hash for a string, as Clojure does: Murmur3.hashInt(s.hashCode()).
+func HashString(s string) uint32 {
+	return murmur3.HashInt32(murmur3.StringHashCode(s))
+}
+
+// HashCombine combines two hash codes, as Clojure's Util.hashCombine.
+func HashCombine(seed uint32, hash uint32) uint32 {
+	// Java shifts an int: >> is arithmetic
+	s := int32(seed)
+	s ^= int32(hash) + int32(-0x61c88647) + s<<6 + s>>2
+	return uint32(s)
 }
 
 // IsHashable returns true if Hash/HashE can compute a hash for this object.
@@ -62,15 +138,17 @@ func IsHashable(v interface{}) bool {
 	}
 
 	switch v.(type) {
-	case bool, int, int8, int32, int64,
-		uint, uint8, uint32, uint64,
+	case bool, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, uintptr,
 		float32, float64,
 		nil,
 		string,
 		complex64, complex128:
 		return true
+	case *big.Int, *big.Rat, *big.Float:
+		return IsNumeric(v)
 	}
-	return false
+	return isReflectHashable(v)
 }
 
 // HashSeq computes a hash for an iseq.Seq
@@ -89,7 +167,7 @@ func HashOrdered(s iseq.Seq) uint32 {
 	hash := uint32(1)
 
 	for ; s != nil; s = s.Next() {
-		hash = 31*hash + Hash(s.First)
+		hash = 31*hash + Hash(s.First())
 		n++
 	}
 	return murmur3.FinalizeCollHash(hash, n)
@@ -101,7 +179,7 @@ func HashUnordered(s iseq.Seq) uint32 {
 	hash := uint32(0)
 
 	for ; s != nil; s = s.Next() {
-		hash += Hash(s.First)
+		hash += Hash(s.First())
 		n++
 	}
 	return murmur3.FinalizeCollHash(hash, n)
@@ -0,0 +1,156 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func bigInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("bad big.Int literal " + s)
+	}
+	return n
+}
+
+// Values of (hash x) from Clojure 1.6+ on the JVM
+var clojureHashTests = []struct {
+	in  interface{}
+	out int32
+}{
+	{nil, 0},
+	{0, 0},
+	{1, 1392991556},
+	{-1, 1651860712},
+	{42, 1871679806},
+	{int8(42), 1871679806},
+	{uint32(42), 1871679806},
+	{int64(-1), 1651860712},
+	{uint64(1) << 63, -2147483648}, // 9223372036854775808N
+	{int16(42), 1871679806},
+	{uint16(42), 1871679806},
+	{uintptr(42), 1871679806},
+	{big.NewInt(42), 1871679806},
+	{big.NewInt(-1), 1651860712},
+	{bigInt("9223372036854775808"), -2147483648},
+	{bigInt("18446744073709551616"), 961},
+	{bigInt("-18446744073709551616"), -961},
+	{big.NewRat(84, 2), 1871679806},
+	{big.NewRat(1, 2), 3},
+	{big.NewRat(-1, 2), -3},
+	{true, 1231},
+	{false, 1237},
+	{1.0, 1072693248},
+	{1.5, 1073217536},
+	{2.0, 1073741824},
+	{0.0, 0},
+	{math.Copysign(0, -1), 0},
+	{math.NaN(), 2146959360},
+	{"", 0},
+	{"a", 1455541201},
+	{"abc", 74834163},
+}
+
+func TestHashMatchesClojure(t *testing.T) {
+	for i, tt := range clojureHashTests {
+		h, err := HashE(tt.in)
+		if err != nil {
+			t.Errorf("%d. HashE(%v) => error %v", i, tt.in, err)
+			continue
+		}
+		if int32(h) != tt.out {
+			t.Errorf("%d. Hash(%v) => %d, want %d", i, tt.in, int32(h), tt.out)
+		}
+	}
+}
+
+// Clojure hashes (float 1.5) as a Float, 1069547520, and 1.5M as a BigDecimal.
+// Here float32 and *big.Float hash as the float64 they equal, 1073217536,
+// so that values that are Equiv to a float64 hash the same as it.
+func TestHashFloatsAsDouble(t *testing.T) {
+	var tests = []interface{}{float32(1.5), big.NewFloat(1.5)}
+	for i, x := range tests {
+		if h, want := Hash(x), Hash(1.5); h != want {
+			t.Errorf("%d. Hash(%v (%T)) => %d, want %d", i, x, x, int32(h), int32(want))
+		}
+	}
+}
+
+func TestHashComplex(t *testing.T) {
+	if Hash(complex64(1+2i)) != Hash(complex128(1+2i)) {
+		t.Errorf("complex64 and complex128 of the same value should hash the same")
+	}
+}
+
+var numEquivTests = []struct {
+	x, y interface{}
+	out  bool
+}{
+	{1, 1, true},
+	{1, int8(1), true},
+	{int64(1), uint64(1), true},
+	{-1, uint64(math.MaxUint64), false},
+	{uint(5), int32(5), true},
+	{1, 1.0, false},
+	{1.0, 1, false},
+	{float32(0.5), 0.5, true},
+	{float32(0.1), 0.1, false},
+	{math.NaN(), math.NaN(), false},
+	{1 + 0i, complex64(1), true},
+	{1 + 0i, 1, false},
+	{1, "1", false},
+	{true, 1, false},
+	{int16(7), uint16(7), true},
+	{uintptr(7), 7, true},
+	{big.NewInt(5), int64(5), true},
+	{uint8(5), big.NewInt(5), true},
+	{big.NewInt(-1), uint64(math.MaxUint64), false},
+	{bigInt("18446744073709551615"), uint64(math.MaxUint64), true},
+	{big.NewRat(10, 2), 5, true},
+	{big.NewRat(1, 2), big.NewRat(2, 4), true},
+	{big.NewRat(1, 2), 0.5, false},
+	{big.NewInt(1), 1.0, false},
+	{big.NewFloat(0.5), 0.5, true},
+	{0.5, big.NewFloat(0.5), true},
+	{big.NewFloat(0.1), float32(0.1), false},
+	{big.NewFloat(2), big.NewFloat(2), true},
+	{big.NewFloat(1), math.NaN(), false},
+}
+
+func TestNumEquiv(t *testing.T) {
+	for i, tt := range numEquivTests {
+		if got := Equiv(tt.x, tt.y); got != tt.out {
+			t.Errorf("%d. Equiv(%v (%T), %v (%T)) => %v, want %v", i, tt.x, tt.x, tt.y, tt.y, got, tt.out)
+		}
+		if got := Equiv(tt.y, tt.x); got != tt.out {
+			t.Errorf("%d. Equiv(%v (%T), %v (%T)) => %v, want %v", i, tt.y, tt.y, tt.x, tt.x, got, tt.out)
+		}
+		if tt.out && Hash(tt.x) != Hash(tt.y) {
+			t.Errorf("%d. Equiv values %v (%T) and %v (%T) hash differently", i, tt.x, tt.x, tt.y, tt.y)
+		}
+	}
+}
+
+func TestBigHashConsistentWithEquiv(t *testing.T) {
+	pairs := [][2]interface{}{
+		{big.NewInt(5), int64(5)},
+		{bigInt("18446744073709551615"), uint64(math.MaxUint64)},
+		{big.NewRat(10, 2), 5},
+		{big.NewRat(1, 3), big.NewRat(2, 6)},
+		{big.NewFloat(0.25), 0.25},
+		{big.NewFloat(0.25), float32(0.25)},
+	}
+	for i, p := range pairs {
+		if !Equiv(p[0], p[1]) {
+			t.Errorf("%d. Equiv(%v, %v) => false, want true", i, p[0], p[1])
+		}
+		if Hash(p[0]) != Hash(p[1]) {
+			t.Errorf("%d. Hash(%v) = %d, Hash(%v) = %d, want equal", i, p[0], Hash(p[0]), p[1], Hash(p[1]))
+		}
+	}
+}
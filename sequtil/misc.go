@@ -6,47 +6,98 @@ package sequtil
 
 import (
 	"github.com/dmiller/go-seq/iseq"
+	"reflect"
 )
 
 // MapCons adds (conses) a new key/value pair onto an iseq.PMap
 // A MapEntry adds its key/value.
 // A PVector uses v[2*i] v[2*i+1] as key/value pairs
-// Otherwise, we need a sequence of iseq.MapEntry values
-// Assumes its argument is one of the above; else panics
+// A Go map adds its entries.
+// Otherwise, we need a sequence of iseq.MapEntry values (which may be a Go slice)
+// Assumes its argument is one of the above; else panics.  See MapConsE.
 func MapCons(m iseq.PMap, o interface{}) iseq.PMap {
+	ret, err := MapConsE(m, o)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// MapConsE is MapCons returning an error instead of panicking on a bad argument.
+func MapConsE(m iseq.PMap, o interface{}) (iseq.PMap, error) {
 	if me, ok := o.(iseq.MapEntry); ok {
-		return m.AssocM(me.Key(), me.Val())
+		return m.AssocM(me.Key(), me.Val()), nil
 	}
 
 	if v, ok := o.(iseq.PVector); ok {
 		if v.Count() != 2 {
-			panic("Vector arg to map cons must be a pair")
+			return nil, &ArgumentError{"MapCons", o, "vector arg to map cons must be a pair"}
+		}
+		return m.AssocM(v.Nth(0), v.Nth(1)), nil
+	}
+
+	if IsGoMap(o) {
+		ret := m
+		for iter := reflect.ValueOf(o).MapRange(); iter.Next(); {
+			ret = ret.AssocM(iter.Key().Interface(), iter.Value().Interface())
 		}
-		return m.AssocM(v.Nth(0), v.Nth(1))
+		return ret, nil
 	}
 
+	s, err := ConvertToSeqE(o)
+	if err != nil {
+		return nil, err
+	}
 	ret := m
-	for s := ConvertToSeq(o); s != nil; s = s.Next() {
-		me := s.First().(iseq.MapEntry)
+	for ; s != nil; s = s.Next() {
+		me, ok := s.First().(iseq.MapEntry)
+		if !ok {
+			return nil, &TypeError{"MapCons", s.First(), "a map entry"}
+		}
 		ret = ret.AssocM(me.Key(), me.Val())
 	}
-	return ret
+	return ret, nil
 }
 
 // ConvertToSeq attempt to convert its argument to an iseq.Seq
-// internal use: assumes the arg can be converted, else panics
+// Handles nil, iseq.Seq, iseq.Seqable, and anything the registered converter handles
+// (package seq registers one for Go slices, arrays, maps, strings, and channels).
+// Panics if the arg can't be converted.  See ConvertToSeqE.
 func ConvertToSeq(o interface{}) iseq.Seq {
-	// TODO: handle more general cases of maps, slices, arrays
+	s, err := ConvertToSeqE(o)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ConvertToSeqE is ConvertToSeq returning a *TypeError if the argument cannot be converted.
+func ConvertToSeqE(o interface{}) (iseq.Seq, error) {
 	if o == nil {
-		return nil
+		return nil, nil
 	}
 	if s, ok := o.(iseq.Seq); ok {
-		return s
+		return s, nil
 	}
 
 	if s, ok := o.(iseq.Seqable); ok {
-		return s.Seq()
+		return s.Seq(), nil
 	}
 
-	panic("Cannot convert object to sequence")
+	if seqConverter != nil {
+		if s, ok := seqConverter(o); ok {
+			return s, nil
+		}
+	}
+
+	return nil, &TypeError{"ConvertToSeq", o, "seqable"}
+}
+
+var seqConverter func(o interface{}) (iseq.Seq, bool)
+
+// RegisterSeqConverter sets a function ConvertToSeq uses for values that are not iseq.Seqable.
+// It returns false for values it does not handle.
+// Package seq registers a converter for native Go values, which sequtil cannot import.
+func RegisterSeqConverter(f func(o interface{}) (iseq.Seq, bool)) {
+	seqConverter = f
 }
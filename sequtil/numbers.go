@@ -0,0 +1,201 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"github.com/dmiller/go-seq/murmur3"
+	"math"
+	"math/big"
+	"math/bits"
+	"reflect"
+)
+
+// Clojure's = on numbers compares values within a category
+// and treats numbers of different categories as unequal:  (= 1 1.0) is false.
+// Here the categories are
+//	integer:  the Go integer types, *big.Int, and *big.Rat with an integer value
+//	ratio:    *big.Rat with a non-integer value
+//	float:    float32, float64, and *big.Float
+//	complex:  complex64, complex128
+// A *big.Rat with an integer value is an integer, as a Clojure ratio normalizes to a long or BigInt.
+//
+// Hash is consistent with these rules: integers hash as Clojure longs (or BigInts, if too big),
+// ratios as Clojure Ratios, and floats as Clojure doubles.
+// Ordering (CompareComparableNumeric) ignores categories and compares values.
+
+type numCategory int
+
+const (
+	notNumeric numCategory = iota
+	integerCategory
+	ratioCategory
+	floatCategory
+	complexCategory
+)
+
+func numericCategory(v interface{}) numCategory {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return integerCategory
+	case *big.Int:
+		if v != nil {
+			return integerCategory
+		}
+	case *big.Rat:
+		if v != nil {
+			if v.IsInt() {
+				return integerCategory
+			}
+			return ratioCategory
+		}
+	case float32, float64:
+		return floatCategory
+	case *big.Float:
+		if v != nil {
+			return floatCategory
+		}
+	case complex64, complex128:
+		return complexCategory
+	}
+	return notNumeric
+}
+
+// IsNumeric returns true if its argument is a Go number or a (non-nil) *big.Int, *big.Rat, or *big.Float.
+func IsNumeric(v interface{}) bool {
+	return numericCategory(v) != notNumeric
+}
+
+func isBig(v interface{}) bool {
+	switch v.(type) {
+	case *big.Int, *big.Rat, *big.Float:
+		return true
+	}
+	return false
+}
+
+// NumEquiv returns true if x and y are numbers in the same category with the same value.
+// So int8(1), uint64(1), and big.NewInt(1) are equivalent, float32(0.5) and 0.5 are equivalent,
+// but 1 and 1.0 are not.
+func NumEquiv(x interface{}, y interface{}) bool {
+	cx, cy := numericCategory(x), numericCategory(y)
+	if cx != cy || cx == notNumeric {
+		return false
+	}
+	switch cx {
+	case integerCategory:
+		if isBig(x) || isBig(y) {
+			return toBigInt(x).Cmp(toBigInt(y)) == 0
+		}
+		return intEquiv(reflect.ValueOf(x), reflect.ValueOf(y))
+	case ratioCategory:
+		return x.(*big.Rat).Cmp(y.(*big.Rat)) == 0
+	case floatCategory:
+		if isBig(x) || isBig(y) {
+			fx, okx := toBigFloat(x)
+			fy, oky := toBigFloat(y)
+			return okx && oky && fx.Cmp(fy) == 0
+		}
+		return reflect.ValueOf(x).Float() == reflect.ValueOf(y).Float()
+	default:
+		return reflect.ValueOf(x).Complex() == reflect.ValueOf(y).Complex()
+	}
+}
+
+func isSigned(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func intEquiv(x reflect.Value, y reflect.Value) bool {
+	switch sx, sy := isSigned(x), isSigned(y); {
+	case sx && sy:
+		return x.Int() == y.Int()
+	case !sx && !sy:
+		return x.Uint() == y.Uint()
+	case sx:
+		return x.Int() >= 0 && uint64(x.Int()) == y.Uint()
+	default:
+		return y.Int() >= 0 && uint64(y.Int()) == x.Uint()
+	}
+}
+
+// Convert a value in the integer category to a *big.Int
+func toBigInt(v interface{}) *big.Int {
+	switch v := v.(type) {
+	case *big.Int:
+		return v
+	case *big.Rat:
+		return v.Num()
+	}
+	rv := reflect.ValueOf(v)
+	if isSigned(rv) {
+		return big.NewInt(rv.Int())
+	}
+	return new(big.Int).SetUint64(rv.Uint())
+}
+
+// Convert a value in the float category to a *big.Float.  Returns false for NaN.
+func toBigFloat(v interface{}) (*big.Float, bool) {
+	if f, ok := v.(*big.Float); ok {
+		return f, true
+	}
+	f := reflect.ValueOf(v).Float()
+	if math.IsNaN(f) {
+		return nil, false
+	}
+	return big.NewFloat(f), true
+}
+
+// HashBigInt computes a hash for a *big.Int.
+// Values that fit in an int64 hash as Clojure longs; larger values hash as Clojure BigInts.
+func HashBigInt(n *big.Int) uint32 {
+	if n.IsInt64() {
+		return murmur3.HashInt64(n.Int64())
+	}
+	return javaBigIntegerHash(n)
+}
+
+// BigInteger.hashCode: over the magnitude's 32-bit words, most significant first, times the sign
+func javaBigIntegerHash(n *big.Int) uint32 {
+	var h uint32
+	words := n.Bits()
+	if bits.UintSize == 64 {
+		for i := len(words) - 1; i >= 0; i-- {
+			w := uint64(words[i])
+			if i < len(words)-1 || w>>32 != 0 {
+				h = 31*h + uint32(w>>32)
+			}
+			h = 31*h + uint32(w)
+		}
+	} else {
+		for i := len(words) - 1; i >= 0; i-- {
+			h = 31*h + uint32(words[i])
+		}
+	}
+	if n.Sign() < 0 {
+		h = -h
+	}
+	return h
+}
+
+// HashBigRat computes a hash for a *big.Rat.
+// Integer values hash as integers; others as Clojure Ratios.
+func HashBigRat(r *big.Rat) uint32 {
+	if r.IsInt() {
+		return HashBigInt(r.Num())
+	}
+	// Ratio.hashCode
+	return javaBigIntegerHash(r.Num()) ^ javaBigIntegerHash(r.Denom())
+}
+
+// HashBigFloat computes a hash for a *big.Float, as for the nearest float64.
+// Equal *big.Floats, and a *big.Float and a float64 with the same value, hash the same.
+func HashBigFloat(f *big.Float) uint32 {
+	x, _ := f.Float64()
+	return HashFloat64(x)
+}
@@ -0,0 +1,293 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/murmur3"
+	"reflect"
+	"sync"
+)
+
+// Native Go values take part in Equiv, Hash, and DefaultCompareFn by reflection:
+//
+//	slices and arrays    are sequential:  equivalent to a PVector, PList, or seq with equivalent elements,
+//	                     hashed as a vector, and ordered as vectors (by length, then elementwise)
+//	maps                 are equivalent to a PMap (or Go map) with equivalent entries, hashed as a map;
+//	                     not ordered
+//	structs              only if registered with RegisterStruct:  equivalent to a struct of the same type
+//	                     with equivalent fields, hashed and ordered by their fields in declaration order
+//
+// Struct fields that are unexported or tagged `seq:"-"` are ignored.
+
+var structRegistry = struct {
+	sync.RWMutex
+	types map[reflect.Type][]int
+}{types: make(map[reflect.Type][]int)}
+
+// RegisterStruct registers the struct type of v for structural equivalence, hashing, and comparison.
+// Panics if v is not a struct.  Pointers are rejected:  a pointer to a registered struct
+// keeps its identity semantics, since hashing through it would change as the struct is mutated.
+func RegisterStruct(v interface{}) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Struct {
+		panic("RegisterStruct requires a struct")
+	}
+
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("seq") == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+
+	structRegistry.Lock()
+	structRegistry.types[t] = fields
+	structRegistry.Unlock()
+}
+
+// structFields returns the indexes of the fields of a registered struct type.
+func structFields(t reflect.Type) ([]int, bool) {
+	structRegistry.RLock()
+	fields, ok := structRegistry.types[t]
+	structRegistry.RUnlock()
+	return fields, ok
+}
+
+// IsGoSequential returns true if its argument is a Go slice or array.
+func IsGoSequential(o interface{}) bool {
+	switch reflect.ValueOf(o).Kind() {
+	case reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// IsGoMap returns true if its argument is a Go map.
+func IsGoMap(o interface{}) bool {
+	return reflect.ValueOf(o).Kind() == reflect.Map
+}
+
+// isRegisteredStruct returns true if its argument is a struct of a registered type.
+func isRegisteredStruct(o interface{}) bool {
+	v := reflect.ValueOf(o)
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := structFields(v.Type())
+	return ok
+}
+
+// identical returns true if o1 == o2, without panicking on uncomparable values such as slices and maps.
+// The check is on the values, not their type:  an array of interface{}, or a struct with an interface{} field,
+// has a comparable type but panics on == if it holds a slice or map.
+// Checking o1 is enough:  == panics only on a part of o1 and the same part of o2 having the same uncomparable type.
+func identical(o1 interface{}, o2 interface{}) bool {
+	t := reflect.TypeOf(o1)
+	if t != nil && t == reflect.TypeOf(o2) && !reflect.ValueOf(o1).Comparable() {
+		return false
+	}
+	return o1 == o2
+}
+
+// SeqSliceEquiv returns true if the sequence and the Go slice or array are element-by-element equivalent.
+func SeqSliceEquiv(s iseq.Seq, o interface{}) bool {
+	v := reflect.ValueOf(o)
+	n := v.Len()
+	i := 0
+	for ; s != nil; s, i = s.Next(), i+1 {
+		if i >= n || !Equiv(s.First(), v.Index(i).Interface()) {
+			return false
+		}
+	}
+	return i == n
+}
+
+// goMapEquiv returns true if the map and the Go map have equivalent keys and values.
+func goMapEquiv(m iseq.PMap, o interface{}) bool {
+	v := reflect.ValueOf(o)
+	if m.Count() != v.Len() {
+		return false
+	}
+	for iter := v.MapRange(); iter.Next(); {
+		k := iter.Key().Interface()
+		if !m.ContainsKey(k) || !Equiv(m.ValAt(k), iter.Value().Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// reflectEquiv handles Equiv when neither argument is iseq.Equivable.
+func reflectEquiv(o1 interface{}, o2 interface{}) bool {
+	v1, v2 := reflect.ValueOf(o1), reflect.ValueOf(o2)
+	switch v1.Kind() {
+	case reflect.Slice, reflect.Array:
+		if k := v2.Kind(); k != reflect.Slice && k != reflect.Array {
+			return false
+		}
+		if v1.Len() != v2.Len() {
+			return false
+		}
+		for i := 0; i < v1.Len(); i++ {
+			if !Equiv(v1.Index(i).Interface(), v2.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Map:
+		if v2.Kind() != reflect.Map || v1.Len() != v2.Len() {
+			return false
+		}
+		for iter := v1.MapRange(); iter.Next(); {
+			val2, ok := goMapLookup(v2, iter.Key())
+			if !ok || !Equiv(iter.Value().Interface(), val2.Interface()) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Struct:
+		if v1.Type() != v2.Type() {
+			return false
+		}
+		fields, ok := structFields(v1.Type())
+		if !ok {
+			return false
+		}
+		for _, i := range fields {
+			if !Equiv(v1.Field(i).Interface(), v2.Field(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// goMapLookup finds the value for an Equiv key in a Go map.
+// Direct lookup is used when the key type fits; otherwise the keys are scanned.
+func goMapLookup(m reflect.Value, key reflect.Value) (reflect.Value, bool) {
+	kt := m.Type().Key()
+	if key.Type().AssignableTo(kt) && kt.Comparable() {
+		if val := m.MapIndex(key); val.IsValid() {
+			return val, true
+		}
+	}
+	k := key.Interface()
+	for iter := m.MapRange(); iter.Next(); {
+		if Equiv(k, iter.Key().Interface()) {
+			return iter.Value(), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// hashReflect handles HashE for Go slices, arrays, maps, and registered structs.
+func hashReflect(o interface{}) (uint32, error) {
+	v := reflect.ValueOf(o)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		hash := uint32(1)
+		for i := 0; i < v.Len(); i++ {
+			h, err := HashE(v.Index(i).Interface())
+			if err != nil {
+				return 0, err
+			}
+			hash = 31*hash + h
+		}
+		return murmur3.FinalizeCollHash(hash, int32(v.Len())), nil
+
+	case reflect.Map:
+		hash := uint32(0)
+		for iter := v.MapRange(); iter.Next(); {
+			hk, err := HashE(iter.Key().Interface())
+			if err != nil {
+				return 0, err
+			}
+			hv, err := HashE(iter.Value().Interface())
+			if err != nil {
+				return 0, err
+			}
+			// as a map entry:  an ordered hash of [key val]
+			hash += murmur3.FinalizeCollHash(31*(31*1+hk)+hv, 2)
+		}
+		return murmur3.FinalizeCollHash(hash, int32(v.Len())), nil
+
+	case reflect.Struct:
+		fields, ok := structFields(v.Type())
+		if !ok {
+			break
+		}
+		hash := uint32(1)
+		for _, i := range fields {
+			h, err := HashE(v.Field(i).Interface())
+			if err != nil {
+				return 0, err
+			}
+			hash = 31*hash + h
+		}
+		return murmur3.FinalizeCollHash(hash, int32(len(fields))), nil
+	}
+	return 0, &TypeError{"Hash", o, "hashable"}
+}
+
+// isReflectHashable returns true if hashReflect handles its argument.
+// Elements are not checked.
+func isReflectHashable(o interface{}) bool {
+	return IsGoSequential(o) || IsGoMap(o) || isRegisteredStruct(o)
+}
+
+// SliceCompare compares a Go slice or array to a Go slice, array, or iseq.PVector.
+// As for vectors, shorter sorts first; equal lengths compare elementwise.
+func SliceCompare(k1 interface{}, k2 interface{}) int {
+	v1 := reflect.ValueOf(k1)
+	var n2 int
+	var nth func(i int) interface{}
+	switch k2 := k2.(type) {
+	case iseq.PVector:
+		n2 = k2.Count1()
+		nth = k2.Nth
+	default:
+		if !IsGoSequential(k2) {
+			panic(&CompareError{k1, k2})
+		}
+		v2 := reflect.ValueOf(k2)
+		n2 = v2.Len()
+		nth = func(i int) interface{} { return v2.Index(i).Interface() }
+	}
+
+	n1 := v1.Len()
+	if n1 < n2 {
+		return -1
+	}
+	if n1 > n2 {
+		return 1
+	}
+	for i := 0; i < n1; i++ {
+		if c := DefaultCompareFn(v1.Index(i).Interface(), nth(i)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareStruct compares two registered structs of the same type, field by field.
+func compareStruct(k1 interface{}, k2 interface{}) int {
+	v1, v2 := reflect.ValueOf(k1), reflect.ValueOf(k2)
+	if v1.Type() != v2.Type() {
+		panic(&CompareError{k1, k2})
+	}
+	fields, _ := structFields(v1.Type())
+	for _, i := range fields {
+		if c := DefaultCompareFn(v1.Field(i).Interface(), v2.Field(i).Interface()); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,136 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sequtil
+
+import (
+	"sort"
+	"testing"
+)
+
+type point struct {
+	X, Y  int
+	Label string `seq:"-"`
+	seen  bool
+}
+
+// boxed has a comparable type, but == panics on it if Val holds a slice or map
+type boxed struct {
+	Name string
+	Val  interface{}
+}
+
+type unregistered struct {
+	A []int
+}
+
+func init() {
+	RegisterStruct(point{})
+	RegisterStruct(boxed{})
+}
+
+var reflectEquivTests = []struct {
+	x, y interface{}
+	out  bool
+}{
+	{[]int{1, 2}, []int{1, 2}, true},
+	{[]int{1, 2}, []int64{1, 2}, true},
+	{[]int{1, 2}, [2]uint8{1, 2}, true},
+	{[]interface{}{"a", []int{1}}, []interface{}{"a", []int{1}}, true},
+	{[]int{1, 2}, []int{2, 1}, false},
+	{[]int{1, 2}, []int{1, 2, 3}, false},
+	{[]int{1}, []float64{1}, false},
+	{[]int{}, []string(nil), true},
+	{[]int{}, map[int]int{}, false},
+	{map[string]int{"a": 1}, map[string]int{"a": 1}, true},
+	{map[string]int{"a": 1}, map[interface{}]interface{}{"a": int64(1)}, true},
+	{map[int]string{1: "a"}, map[int64]string{1: "a"}, true},
+	{map[string]int{"a": 1}, map[string]int{"a": 2}, false},
+	{map[string]int{"a": 1}, map[string]int{"b": 1}, false},
+	{point{1, 2, "p", true}, point{1, 2, "q", false}, true},
+	{point{1, 2, "", false}, point{2, 1, "", false}, false},
+	{unregistered{[]int{1}}, unregistered{[]int{1}}, false},
+	{"abc", []rune("abc"), false},
+	{[1]interface{}{[]int{1}}, [1]interface{}{[]int{1}}, true},
+	{[2]interface{}{1, map[string]int{"a": 1}}, [2]interface{}{1, map[string]int{"a": 2}}, false},
+	{boxed{"a", []int{1}}, boxed{"a", []int{1}}, true},
+	{boxed{"a", []int{1}}, boxed{"a", []int{2}}, false},
+	{boxed{"a", map[int]int{}}, boxed{"a", 1}, false},
+}
+
+func TestReflectEquiv(t *testing.T) {
+	for i, tt := range reflectEquivTests {
+		if e := Equiv(tt.x, tt.y); e != tt.out {
+			t.Errorf("%d. Equiv(%v, %v) => %v, want %v", i, tt.x, tt.y, e, tt.out)
+		}
+		if e := Equiv(tt.y, tt.x); e != tt.out {
+			t.Errorf("%d. Equiv(%v, %v) => %v, want %v", i, tt.y, tt.x, e, tt.out)
+		}
+		if tt.out && Hash(tt.x) != Hash(tt.y) {
+			t.Errorf("%d. Hash(%v) = %d, Hash(%v) = %d, want equal", i, tt.x, Hash(tt.x), tt.y, Hash(tt.y))
+		}
+	}
+}
+
+func TestReflectHashable(t *testing.T) {
+	for i, v := range []interface{}{[]int{1}, [0]string{}, map[string]int{}, point{}} {
+		if !IsHashable(v) {
+			t.Errorf("%d. IsHashable(%v) => false, want true", i, v)
+		}
+	}
+	if IsHashable(unregistered{}) {
+		t.Errorf("IsHashable(unregistered struct) => true, want false")
+	}
+	if _, err := HashE([]interface{}{1, unregistered{}}); err == nil {
+		t.Errorf("HashE of a slice with an unhashable element should fail")
+	}
+}
+
+func TestReflectCompare(t *testing.T) {
+	tests := []struct {
+		x, y interface{}
+		out  int
+	}{
+		{[]int{1, 2}, []int{1, 2}, 0},
+		{[]int{1, 2}, []int{1, 3}, -1},
+		{[]int{9}, []int{1, 2}, -1},
+		{[2]string{"b", "a"}, []string{"a", "b"}, 1},
+		{point{1, 2, "", false}, point{1, 3, "", false}, -1},
+		{point{2, 0, "z", false}, point{1, 9, "a", false}, 1},
+		{[1]interface{}{[]int{1}}, [1]interface{}{[]int{1}}, 0},
+		{[1]interface{}{[]int{1}}, [1]interface{}{[]int{2}}, -1},
+		{boxed{"a", []int{1}}, boxed{"a", []int{1}}, 0},
+		{boxed{"a", []int{2}}, boxed{"a", []int{1}}, 1},
+	}
+	for i, tt := range tests {
+		if c := DefaultCompareFn(tt.x, tt.y); c != tt.out {
+			t.Errorf("%d. DefaultCompareFn(%v, %v) => %d, want %d", i, tt.x, tt.y, c, tt.out)
+		}
+	}
+
+	pts := []interface{}{point{2, 1, "", false}, point{1, 2, "", false}, point{1, 1, "", false}}
+	sort.Slice(pts, func(i, j int) bool { return DefaultCompareFn(pts[i], pts[j]) < 0 })
+	if !Equiv(pts, []point{{1, 1, "", false}, {1, 2, "", false}, {2, 1, "", false}}) {
+		t.Errorf("sorted points => %v", pts)
+	}
+}
+
+func TestRegisterStructPointer(t *testing.T) {
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("RegisterStruct of a pointer did not panic")
+			}
+		}()
+		RegisterStruct(&unregistered{})
+	}()
+	if IsHashable(unregistered{}) {
+		t.Error("RegisterStruct of a pointer registered its element type")
+	}
+
+	p1, p2 := &point{1, 2, "", false}, &point{1, 2, "", false}
+	if Equiv(p1, p2) || !Equiv(p1, p1) {
+		t.Errorf("pointers to registered structs should be equivalent only if identical")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"sync/atomic"
+)
+
+// An Atom holds a value that can be changed synchronously and independently of other Atoms and Refs.
+// Changes are made with compare-and-set, so no transaction is needed (or used).
+type Atom struct {
+	state atomic.Pointer[atomBox]
+}
+
+// Boxing the value lets us compare-and-set on a pointer no matter the type of value.
+type atomBox struct {
+	val interface{}
+}
+
+// NewAtom returns an Atom with the given initial value.
+func NewAtom(val interface{}) *Atom {
+	a := new(Atom)
+	a.state.Store(&atomBox{val})
+	return a
+}
+
+// Deref returns the current value.
+func (a *Atom) Deref() interface{} {
+	return a.state.Load().val
+}
+
+// Reset sets the value, regardless of the current value.  Returns the new value.
+func (a *Atom) Reset(val interface{}) interface{} {
+	a.state.Store(&atomBox{val})
+	return val
+}
+
+// Swap sets the value to fn(current value, args...).
+// fn may be called more than once if other goroutines change the Atom concurrently,
+// so it should be free of side effects.
+// Returns the new value.
+func (a *Atom) Swap(fn CFn, args ...interface{}) interface{} {
+	for {
+		old := a.state.Load()
+		val := fn(old.val, args...)
+		if a.state.CompareAndSwap(old, &atomBox{val}) {
+			return val
+		}
+	}
+}
+
+// CompareAndSet sets the value to newVal if the current value is == oldVal.
+// Returns true if the value was set.
+// Like any ==, this panics if the values are of the same uncomparable type.
+func (a *Atom) CompareAndSet(oldVal interface{}, newVal interface{}) bool {
+	old := a.state.Load()
+	if old.val != oldVal {
+		return false
+	}
+	return a.state.CompareAndSwap(old, &atomBox{newVal})
+}
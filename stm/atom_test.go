@@ -0,0 +1,70 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAtomResetAndDeref(t *testing.T) {
+	a := NewAtom(1)
+
+	if v := a.Deref(); v != 1 {
+		t.Errorf("Expected 1, got %v", v)
+	}
+
+	if v := a.Reset("abc"); v != "abc" {
+		t.Errorf("Reset should return the new value, got %v", v)
+	}
+
+	if v := a.Deref(); v != "abc" {
+		t.Errorf("Expected abc, got %v", v)
+	}
+
+	a.Reset(nil)
+	if v := a.Deref(); v != nil {
+		t.Errorf("Expected nil, got %v", v)
+	}
+}
+
+func TestAtomCompareAndSet(t *testing.T) {
+	a := NewAtom(1)
+
+	if a.CompareAndSet(2, 3) {
+		t.Errorf("CompareAndSet with wrong old value should fail")
+	}
+
+	if !a.CompareAndSet(1, 3) {
+		t.Errorf("CompareAndSet with correct old value should succeed")
+	}
+
+	if v := a.Deref(); v != 3 {
+		t.Errorf("Expected 3, got %v", v)
+	}
+}
+
+func TestAtomConcurrentSwap(t *testing.T) {
+	a := NewAtom(0)
+	incr := func(old interface{}, args ...interface{}) interface{} {
+		return old.(int) + args[0].(int)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				a.Swap(incr, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := a.Deref(); v != 1000 {
+		t.Errorf("Expected 1000, got %v", v)
+	}
+}
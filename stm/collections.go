@@ -0,0 +1,252 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"github.com/dmiller/go-seq/iseq"
+	"github.com/dmiller/go-seq/seq"
+	"github.com/dmiller/go-seq/sequtil"
+)
+
+// Transactional collections
+//
+// TMap, TVector, and TSet hold a persistent collection in a Ref (or, for a striped TMap, several Refs).
+// Operations taking a Tx are transactional, with the usual conflict rules:
+// Put, Delete, Assoc, and Pop alter the Ref; the counter and conj operations commute,
+// so concurrent transactions using only them do not conflict.
+// As with any Ref, a transaction that commutes a collection can't then alter it.
+// Snapshot reads the collection outside of any transaction.
+
+// A TMap is a transactional map.
+type TMap struct {
+	stripes []*Ref
+}
+
+// NewTMap returns an empty TMap held in a single Ref.
+func NewTMap() *TMap {
+	return NewStripedTMap(1)
+}
+
+// NewStripedTMap returns an empty TMap whose keys are spread by hash over n Refs.
+// Transactions writing keys in different stripes do not conflict,
+// at the cost of reading all n Refs for Count and Snapshot.
+func NewStripedTMap(n int) *TMap {
+	if n < 1 {
+		n = 1
+	}
+	m := &TMap{stripes: make([]*Ref, n)}
+	for i := range m.stripes {
+		m.stripes[i] = NewRef(seq.EmptyPHashMap)
+	}
+	return m
+}
+
+func (m *TMap) stripe(key interface{}) *Ref {
+	if len(m.stripes) == 1 {
+		return m.stripes[0]
+	}
+	return m.stripes[sequtil.Hash(key)%uint32(len(m.stripes))]
+}
+
+func mapIn(tx *Tx, r *Ref) *seq.PHashMap {
+	return r.Deref(tx).(*seq.PHashMap)
+}
+
+// Get returns the value for key, or nil if key is not present.
+func (m *TMap) Get(tx *Tx, key interface{}) interface{} {
+	return mapIn(tx, m.stripe(key)).ValAt(key)
+}
+
+// GetD returns the value for key, or notFound if key is not present.
+func (m *TMap) GetD(tx *Tx, key interface{}, notFound interface{}) interface{} {
+	return mapIn(tx, m.stripe(key)).ValAtD(key, notFound)
+}
+
+// ContainsKey returns true if key is present.
+func (m *TMap) ContainsKey(tx *Tx, key interface{}) bool {
+	return mapIn(tx, m.stripe(key)).ContainsKey(key)
+}
+
+func assocFn(v interface{}, args ...interface{}) interface{} {
+	return v.(*seq.PHashMap).AssocM(args[0], args[1])
+}
+
+func withoutFn(v interface{}, args ...interface{}) interface{} {
+	return v.(*seq.PHashMap).Without(args[0])
+}
+
+// Put associates key with val.
+func (m *TMap) Put(tx *Tx, key interface{}, val interface{}) {
+	m.stripe(key).Alter(tx, assocFn, key, val)
+}
+
+// Delete removes key.
+func (m *TMap) Delete(tx *Tx, key interface{}) {
+	m.stripe(key).Alter(tx, withoutFn, key)
+}
+
+func incrFn(v interface{}, args ...interface{}) interface{} {
+	pm := v.(*seq.PHashMap)
+	x := pm.ValAtD(args[0], 0)
+	n, ok := x.(int)
+	if !ok {
+		panic(&sequtil.TypeError{Op: "TMap.Incr", Value: x, Want: "an int"})
+	}
+	return pm.AssocM(args[0], n+args[1].(int))
+}
+
+// Incr adds delta to the int value of key (treating a missing key as 0), by commuting.
+// Panics with a *sequtil.TypeError if the value of key is not an int.
+//
+// Incr does not return the new value: because it commutes, the value this transaction
+// sees is not necessarily the value it commits.  A transaction that needs the result
+// should Get the key and Put the sum instead, at the cost of conflicting with other writers.
+func (m *TMap) Incr(tx *Tx, key interface{}, delta int) {
+	m.stripe(key).Commute(tx, incrFn, key, delta)
+}
+
+// Count returns the number of entries.
+func (m *TMap) Count(tx *Tx) int {
+	n := 0
+	for _, r := range m.stripes {
+		n += mapIn(tx, r).Count()
+	}
+	return n
+}
+
+// Snapshot returns the current contents, read consistently across stripes.
+func (m *TMap) Snapshot() *seq.PHashMap {
+	if len(m.stripes) == 1 {
+		return m.stripes[0].Value().(*seq.PHashMap)
+	}
+	v, err := RunReadOnly(func(tx *Tx) interface{} {
+		var ret iseq.PMap = seq.EmptyPHashMap
+		for _, r := range m.stripes {
+			for s := mapIn(tx, r).Seq(); s != nil; s = s.Next() {
+				e := s.First().(iseq.MapEntry)
+				ret = ret.AssocM(e.Key(), e.Val())
+			}
+		}
+		return ret
+	})
+	if err != nil {
+		panic(err)
+	}
+	return v.(*seq.PHashMap)
+}
+
+// A TVector is a transactional vector.
+type TVector struct {
+	ref *Ref
+}
+
+// NewTVector returns a TVector holding the given items.
+func NewTVector(items ...interface{}) *TVector {
+	return &TVector{ref: NewRef(seq.NewPVectorFromSlice(items))}
+}
+
+func (v *TVector) vec(tx *Tx) *seq.PVector {
+	return v.ref.Deref(tx).(*seq.PVector)
+}
+
+// Ref returns the Ref holding the vector.
+func (v *TVector) Ref() *Ref {
+	return v.ref
+}
+
+// Nth returns the i-th item, or nil if i is out of range.
+func (v *TVector) Nth(tx *Tx, i int) interface{} {
+	return v.vec(tx).Nth(i)
+}
+
+// Count returns the number of items.
+func (v *TVector) Count(tx *Tx) int {
+	return v.vec(tx).Count()
+}
+
+// Assoc sets the i-th item.  i may be the count, to append.  Panics if i is out of range.
+func (v *TVector) Assoc(tx *Tx, i int, val interface{}) {
+	v.ref.Alter(tx, func(x interface{}, args ...interface{}) interface{} {
+		return x.(*seq.PVector).AssocN(i, val)
+	})
+}
+
+func conjVFn(v interface{}, args ...interface{}) interface{} {
+	return v.(*seq.PVector).ConsV(args[0])
+}
+
+// Conj appends val, by commuting.
+// Concurrent Conjs do not conflict, so their relative order is the commit order.
+func (v *TVector) Conj(tx *Tx, val interface{}) {
+	v.ref.Commute(tx, conjVFn, val)
+}
+
+// Pop removes and returns the last item.  Panics if the vector is empty.
+func (v *TVector) Pop(tx *Tx) interface{} {
+	var last interface{}
+	v.ref.Alter(tx, func(x interface{}, args ...interface{}) interface{} {
+		pv := x.(*seq.PVector)
+		last = pv.Peek()
+		return pv.Pop()
+	})
+	return last
+}
+
+// Snapshot returns the current contents.
+func (v *TVector) Snapshot() *seq.PVector {
+	return v.ref.Value().(*seq.PVector)
+}
+
+// A TSet is a transactional set.
+type TSet struct {
+	ref *Ref
+}
+
+// NewTSet returns a TSet holding the given items.
+func NewTSet(items ...interface{}) *TSet {
+	return &TSet{ref: NewRef(seq.NewPHashSetFromSlice(items))}
+}
+
+func (s *TSet) set(tx *Tx) *seq.PHashSet {
+	return s.ref.Deref(tx).(*seq.PHashSet)
+}
+
+// Ref returns the Ref holding the set.
+func (s *TSet) Ref() *Ref {
+	return s.ref
+}
+
+// Contains returns true if key is in the set.
+func (s *TSet) Contains(tx *Tx, key interface{}) bool {
+	return s.set(tx).Contains(key)
+}
+
+// Count returns the number of items.
+func (s *TSet) Count(tx *Tx) int {
+	return s.set(tx).Count()
+}
+
+func conjSFn(v interface{}, args ...interface{}) interface{} {
+	return v.(*seq.PHashSet).ConjS(args[0])
+}
+
+func disjSFn(v interface{}, args ...interface{}) interface{} {
+	return v.(*seq.PHashSet).DisjoinS(args[0])
+}
+
+// Conj adds key, by commuting.
+func (s *TSet) Conj(tx *Tx, key interface{}) {
+	s.ref.Commute(tx, conjSFn, key)
+}
+
+// Delete removes key.
+func (s *TSet) Delete(tx *Tx, key interface{}) {
+	s.ref.Alter(tx, disjSFn, key)
+}
+
+// Snapshot returns the current contents.
+func (s *TSet) Snapshot() *seq.PHashSet {
+	return s.ref.Value().(*seq.PHashSet)
+}
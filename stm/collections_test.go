@@ -0,0 +1,149 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"github.com/dmiller/go-seq/sequtil"
+	"sync"
+	"testing"
+)
+
+func TestTMap(t *testing.T) {
+	for _, stripes := range []int{1, 8} {
+		m := NewStripedTMap(stripes)
+		RunInTransaction(func(tx *Tx) interface{} {
+			m.Put(tx, "a", 1)
+			m.Put(tx, "b", 2)
+			m.Put(tx, "c", 3)
+			m.Delete(tx, "b")
+			return nil
+		})
+		RunInTransaction(func(tx *Tx) interface{} {
+			if m.Get(tx, "a") != 1 || m.Get(tx, "b") != nil || m.GetD(tx, "b", -1) != -1 {
+				t.Errorf("%d stripes: Get a, b => %v, %v", stripes, m.Get(tx, "a"), m.Get(tx, "b"))
+			}
+			if !m.ContainsKey(tx, "c") || m.ContainsKey(tx, "b") {
+				t.Errorf("%d stripes: ContainsKey wrong", stripes)
+			}
+			if m.Count(tx) != 2 {
+				t.Errorf("%d stripes: Count => %d, want 2", stripes, m.Count(tx))
+			}
+			return nil
+		})
+		snap := m.Snapshot()
+		if snap.Count() != 2 || snap.ValAt("c") != 3 {
+			t.Errorf("%d stripes: Snapshot has %d entries, c = %v", stripes, snap.Count(), snap.ValAt("c"))
+		}
+	}
+}
+
+func TestTMapConcurrentIncr(t *testing.T) {
+	m := NewStripedTMap(4)
+	keys := []string{"w", "x", "y", "z"}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_, err := RunInTransaction(func(tx *Tx) interface{} {
+					for _, k := range keys {
+						m.Incr(tx, k, 1)
+					}
+					return nil
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	snap := m.Snapshot()
+	for _, k := range keys {
+		if v := snap.ValAt(k); v != 400 {
+			t.Errorf("counter %s = %v, want 400", k, v)
+		}
+	}
+}
+
+func TestTMapIncrNonInt(t *testing.T) {
+	m := NewTMap()
+	RunInTransaction(func(tx *Tx) interface{} {
+		m.Put(tx, "a", "one")
+		return nil
+	})
+	defer func() {
+		if _, ok := recover().(*sequtil.TypeError); !ok {
+			t.Error("Incr of a non-int value did not panic with a TypeError")
+		}
+	}()
+	RunInTransaction(func(tx *Tx) interface{} {
+		m.Incr(tx, "a", 1)
+		return nil
+	})
+}
+
+func TestTVector(t *testing.T) {
+	v := NewTVector(1, 2)
+	_, err := RunInTransaction(func(tx *Tx) interface{} {
+		v.Assoc(tx, 0, 10)
+		v.Conj(tx, 3)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Altering after commuting is an error, as for any Ref
+	_, err = RunInTransaction(func(tx *Tx) interface{} {
+		v.Conj(tx, 4)
+		v.Assoc(tx, 0, 11)
+		return nil
+	})
+	if !errors.Is(err, ErrSetAfterCommute) {
+		t.Errorf("Assoc after Conj => %v, want ErrSetAfterCommute", err)
+	}
+	var popped interface{}
+	RunInTransaction(func(tx *Tx) interface{} {
+		if v.Count(tx) != 3 || v.Nth(tx, 0) != 10 || v.Nth(tx, 2) != 3 {
+			t.Errorf("vector contents wrong: count %d", v.Count(tx))
+		}
+		popped = v.Pop(tx)
+		return nil
+	})
+	if popped != 3 {
+		t.Errorf("Pop => %v, want 3", popped)
+	}
+	if s := v.Snapshot(); s.Count() != 2 || s.Nth(1) != 2 {
+		t.Errorf("Snapshot has %d items", s.Count())
+	}
+}
+
+func TestTSet(t *testing.T) {
+	s := NewTSet("a")
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				RunInTransaction(func(tx *Tx) interface{} {
+					s.Conj(tx, g*100+i)
+					return nil
+				})
+			}
+		}(g)
+	}
+	wg.Wait()
+	RunInTransaction(func(tx *Tx) interface{} {
+		s.Delete(tx, "a")
+		return nil
+	})
+	snap := s.Snapshot()
+	if snap.Count() != 100 || snap.Contains("a") || !snap.Contains(324) {
+		t.Errorf("Snapshot has %d items", snap.Count())
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+// A CommitLog records writes to its Refs as transactions commit,
+// before the new values are visible to any other transaction:  a write-ahead hook.
+// Attach one to a Ref with Ref.SetCommitLog.
+//
+// The log function is called with the commit point and the writes to the log's Refs,
+// in order of Ref creation, while the transaction holds the write locks on its Refs.
+// It must not run a transaction on them.
+// If it returns an error, the transaction is not committed and Tx.Run returns a *CommitLogError.
+// A transaction writing Refs with different logs calls each log once,
+// ordered by the first Ref written to each;  if one fails, the logs already called
+// have recorded writes that were not committed.
+type CommitLog struct {
+	log func(point uint64, writes []RefWrite) error
+}
+
+// NewCommitLog returns a CommitLog that calls log for each commit that writes its Refs.
+func NewCommitLog(log func(point uint64, writes []RefWrite) error) *CommitLog {
+	return &CommitLog{log: log}
+}
+
+// SetCommitLog attaches a commit log to the Ref, replacing any other.  A nil l detaches it.
+func (r *Ref) SetCommitLog(l *CommitLog) {
+	r.enterWriteLock()
+	defer r.exitWriteLock()
+	r.commitLog = l
+}
+
+// Write the commit to the logs of the Refs being written.
+// The Refs must be write-locked, and their new values not yet set.
+// Transactions writing no logged Ref do no work beyond a check of each Ref.
+func writeCommitLogs(point uint64, refs []*Ref, vals map[*Ref]interface{}) error {
+	var logs []*CommitLog
+	var writes map[*CommitLog][]RefWrite
+	for _, r := range refs {
+		l := r.commitLog
+		if l == nil {
+			continue
+		}
+		if writes == nil {
+			writes = make(map[*CommitLog][]RefWrite)
+		}
+		if _, ok := writes[l]; !ok {
+			logs = append(logs, l)
+		}
+		writes[l] = append(writes[l], RefWrite{Ref: r, Old: r.tryGetVal(), New: vals[r]})
+	}
+	for _, l := range logs {
+		if err := l.log(point, writes[l]); err != nil {
+			return &CommitLogError{Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCommitLogSeesItsRefs(t *testing.T) {
+	r1 := NewRef(1)
+	r2 := NewRef(2)
+	r3 := NewRef(3)
+
+	var points []uint64
+	var logged [][]RefWrite
+	l := NewCommitLog(func(point uint64, writes []RefWrite) error {
+		points = append(points, point)
+		logged = append(logged, writes)
+		return nil
+	})
+	r1.SetCommitLog(l)
+	r3.SetCommitLog(l)
+
+	obs := new(recordingObserver)
+	RunInTransactionWithOptions(TxOptions{Observer: obs}, func(tx *Tx) interface{} {
+		r3.Set(tx, 30)
+		r2.Set(tx, 20)
+		r1.Set(tx, 10)
+		return nil
+	})
+
+	if len(logged) != 1 {
+		t.Fatalf("Expected the log to be called once, got %d", len(logged))
+	}
+	if len(obs.commits) != 1 || points[0] != obs.commits[0] {
+		t.Errorf("Expected the log to see commit point %v, got %v", obs.commits, points)
+	}
+	w := logged[0]
+	if len(w) != 2 || w[0].Ref != r1 || w[0].Old != 1 || w[0].New != 10 || w[1].Ref != r3 || w[1].New != 30 {
+		t.Errorf("Expected writes to r1 and r3, got %v", w)
+	}
+
+	// not called for a transaction writing none of its Refs
+	RunInTransaction(func(tx *Tx) interface{} { return r2.Set(tx, 200) })
+	if len(logged) != 1 {
+		t.Errorf("Expected the log not to be called, got %d calls", len(logged))
+	}
+
+	// not called once detached
+	r1.SetCommitLog(nil)
+	r3.SetCommitLog(nil)
+	RunInTransaction(func(tx *Tx) interface{} { return r1.Set(tx, 100) })
+	if len(logged) != 1 {
+		t.Errorf("Expected a detached log not to be called, got %d calls", len(logged))
+	}
+}
+
+func TestCommitLogFailurePreventsCommit(t *testing.T) {
+	errFull := errors.New("disk full")
+	r1 := NewRef(1)
+	r2 := NewRef(2)
+	r1.SetCommitLog(NewCommitLog(func(point uint64, writes []RefWrite) error {
+		return errFull
+	}))
+	sub := SubscribeWith(SubscribeOptions{Buffer: 1}, r2)
+	defer Unsubscribe(sub)
+
+	_, e := RunInTransaction(func(tx *Tx) interface{} {
+		r1.Set(tx, 10)
+		r2.Set(tx, 20)
+		return nil
+	})
+
+	if !errors.Is(e, ErrCommitLog) || !errors.Is(e, errFull) {
+		t.Errorf("Expected ErrCommitLog wrapping the log's error, got %v", e)
+	}
+	var cle *CommitLogError
+	if !errors.As(e, &cle) {
+		t.Errorf("Expected a *CommitLogError, got %T", e)
+	}
+	if v1, v2 := r1.Deref(nil), r2.Deref(nil); v1 != 1 || v2 != 2 {
+		t.Errorf("Expected nothing committed, got %v and %v", v1, v2)
+	}
+	select {
+	case ev := <-sub:
+		t.Errorf("Expected no commit event, got %v", ev)
+	default:
+	}
+
+	// the feed lock was released
+	RunInTransaction(func(tx *Tx) interface{} { return r2.Set(tx, 21) })
+	if ev := <-sub; ev.Writes[0].New != 21 {
+		t.Errorf("Expected an event for the next commit, got %v", ev)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDeref is implemented by references whose value can be read outside a transaction:
+// Ref, Atom, Var, Delay, Promise, and Future.
+type IDeref interface {
+	// Value returns the current value, computing or waiting for it if necessary.
+	Value() interface{}
+}
+
+// IBlockingDeref is implemented by references whose value may not be available yet:
+// Promise and Future.
+type IBlockingDeref interface {
+	IDeref
+
+	// ValueTimeout waits up to d for the value.  Returns timeoutVal if it is not available in time.
+	ValueTimeout(d time.Duration, timeoutVal interface{}) interface{}
+}
+
+// Value returns the most recently committed value of the Ref.
+func (r *Ref) Value() interface{} {
+	return r.currentVal()
+}
+
+// Value returns the current value of the Atom.
+func (a *Atom) Value() interface{} {
+	return a.Deref()
+}
+
+// ErrCancelled is the error of a Future that was cancelled before it finished.
+var ErrCancelled = errors.New("Future cancelled")
+
+// A Delay computes its value on the first call to Value and caches it.
+// If the computation panics, every call to Value panics with the same value.
+type Delay struct {
+	lock     sync.Mutex
+	fn       func() interface{}
+	val      interface{}
+	panicVal interface{}
+	realized bool
+}
+
+// NewDelay returns a Delay that will compute its value with fn.
+func NewDelay(fn func() interface{}) *Delay {
+	return &Delay{fn: fn}
+}
+
+// Value computes the value if it has not been computed, then returns it.
+func (d *Delay) Value() interface{} {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if !d.realized {
+		func() {
+			defer func() {
+				d.panicVal = recover()
+				d.realized = true
+				d.fn = nil
+			}()
+			d.val = d.fn()
+		}()
+	}
+	if d.panicVal != nil {
+		panic(d.panicVal)
+	}
+	return d.val
+}
+
+// Realized returns true if the value has been computed.
+func (d *Delay) Realized() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.realized
+}
+
+// A Promise is a value delivered once, by any goroutine.
+// Readers block until it is delivered.
+type Promise struct {
+	latch *CountDownLatch
+	once  sync.Once
+	val   interface{}
+}
+
+// NewPromise returns an undelivered Promise.
+func NewPromise() *Promise {
+	return &Promise{latch: NewCountDownLatch(1)}
+}
+
+// Deliver sets the value of the Promise and wakes its readers.
+// Returns false (and does nothing) if a value was already delivered.
+func (p *Promise) Deliver(val interface{}) bool {
+	delivered := false
+	p.once.Do(func() {
+		p.val = val
+		p.latch.CountDown()
+		delivered = true
+	})
+	return delivered
+}
+
+// Value waits for the value to be delivered and returns it.
+func (p *Promise) Value() interface{} {
+	<-p.latch.Done()
+	return p.val
+}
+
+// ValueTimeout waits up to d for the value to be delivered.
+// Returns timeoutVal if it is not delivered in time.
+//
+// This waits on the latch's Done channel rather than CountDownLatch.Await.
+// Await may be called only once per latch, and a CountDown after a timed-out
+// Await panics, but a Promise may have any number of readers, each timing out
+// and trying again.
+func (p *Promise) ValueTimeout(d time.Duration, timeoutVal interface{}) interface{} {
+	if p.Realized() {
+		return p.val
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-p.latch.Done():
+		return p.val
+	case <-timer.C:
+		return timeoutVal
+	}
+}
+
+// Realized returns true if the value has been delivered.
+func (p *Promise) Realized() bool {
+	select {
+	case <-p.latch.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// A Future computes its value on its own goroutine.
+// It can be cancelled through its context.
+type Future struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	val    interface{}
+	err    error
+}
+
+// A FutureFn computes the value of a Future.
+// It should return promptly, with ctx.Err(), when ctx is cancelled.
+type FutureFn func(ctx context.Context) (interface{}, error)
+
+// NewFuture starts computing fn on a new goroutine.
+func NewFuture(fn FutureFn) *Future {
+	return NewFutureContext(context.Background(), fn)
+}
+
+// NewFutureContext starts computing fn on a new goroutine, with a context derived from ctx.
+// Cancelling ctx cancels the Future.  The Var bindings of ctx are visible to fn.
+func NewFutureContext(ctx context.Context, fn FutureFn) *Future {
+	ctx, cancel := context.WithCancel(ctx)
+	f := &Future{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer cancel()
+		defer close(f.done)
+		defer func() {
+			if r := recover(); r != nil {
+				f.val, f.err = nil, fmt.Errorf("Future panicked: %v", r)
+			}
+		}()
+		f.val, f.err = fn(ctx)
+		if ctx.Err() != nil && f.err == nil {
+			f.val, f.err = nil, ErrCancelled
+		}
+	}()
+	return f
+}
+
+// Result waits for the Future to finish and returns its value and error.
+// A cancelled Future returns ErrCancelled (or the error its function returned).
+func (f *Future) Result() (interface{}, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+// Value waits for the Future to finish and returns its value.
+// Panics with the error if the Future failed or was cancelled.
+func (f *Future) Value() interface{} {
+	v, err := f.Result()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ValueTimeout waits up to d for the Future to finish.
+// Returns timeoutVal if it does not finish in time; panics with the error if it failed.
+func (f *Future) ValueTimeout(d time.Duration, timeoutVal interface{}) interface{} {
+	if f.Realized() {
+		return f.Value()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-f.done:
+		return f.Value()
+	case <-timer.C:
+		return timeoutVal
+	}
+}
+
+// Cancel asks the Future to stop.  Returns false if it had already finished.
+func (f *Future) Cancel() bool {
+	if f.Realized() {
+		return false
+	}
+	f.cancel()
+	return true
+}
+
+// Realized returns true if the Future has finished (including by cancellation).
+func (f *Future) Realized() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
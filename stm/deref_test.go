@@ -0,0 +1,163 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIDerefImplementations(t *testing.T) {
+	p := NewPromise()
+	p.Deliver(5)
+	f := NewFuture(func(ctx context.Context) (interface{}, error) { return 5, nil })
+	derefs := []IDeref{
+		NewRef(5),
+		NewAtom(5),
+		NewDelay(func() interface{} { return 5 }),
+		p,
+		f,
+	}
+	for i, d := range derefs {
+		if v := d.Value(); v != 5 {
+			t.Errorf("%d. %T.Value() => %v, want 5", i, d, v)
+		}
+	}
+	for i, d := range []IBlockingDeref{p, f} {
+		if v := d.ValueTimeout(time.Second, -1); v != 5 {
+			t.Errorf("%d. %T.ValueTimeout() => %v, want 5", i, d, v)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	calls := 0
+	d := NewDelay(func() interface{} {
+		calls++
+		return calls
+	})
+	if d.Realized() {
+		t.Error("Delay realized before Value")
+	}
+	for i := 0; i < 3; i++ {
+		if v := d.Value(); v != 1 {
+			t.Errorf("Value() => %v, want 1", v)
+		}
+	}
+	if !d.Realized() || calls != 1 {
+		t.Errorf("after Value, realized = %v, calls = %d; want true, 1", d.Realized(), calls)
+	}
+}
+
+func TestDelayPanic(t *testing.T) {
+	d := NewDelay(func() interface{} { panic("boom") })
+	for i := 0; i < 2; i++ {
+		func() {
+			defer func() {
+				if r := recover(); r != "boom" {
+					t.Errorf("Value panicked with %v, want boom", r)
+				}
+			}()
+			d.Value()
+		}()
+	}
+}
+
+func TestPromise(t *testing.T) {
+	p := NewPromise()
+	if v := p.ValueTimeout(10*time.Millisecond, "timeout"); v != "timeout" {
+		t.Errorf("undelivered ValueTimeout => %v, want timeout", v)
+	}
+	if p.Realized() {
+		t.Error("undelivered Promise is realized")
+	}
+
+	got := make(chan interface{}, 3)
+	for i := 0; i < 3; i++ {
+		go func() { got <- p.Value() }()
+	}
+	if !p.Deliver(7) {
+		t.Error("first Deliver => false")
+	}
+	if p.Deliver(8) {
+		t.Error("second Deliver => true")
+	}
+	for i := 0; i < 3; i++ {
+		if v := <-got; v != 7 {
+			t.Errorf("Value() => %v, want 7", v)
+		}
+	}
+}
+
+func TestPromiseTimedReaders(t *testing.T) {
+	p := NewPromise()
+	for i := 0; i < 2; i++ {
+		if v := p.ValueTimeout(time.Millisecond, "timeout"); v != "timeout" {
+			t.Errorf("%d. undelivered ValueTimeout => %v, want timeout", i, v)
+		}
+	}
+
+	got := make(chan interface{}, 3)
+	for i := 0; i < 3; i++ {
+		go func() { got <- p.ValueTimeout(time.Second, "timeout") }()
+	}
+	p.Deliver(7)
+	for i := 0; i < 3; i++ {
+		if v := <-got; v != 7 {
+			t.Errorf("%d. ValueTimeout() => %v, want 7", i, v)
+		}
+	}
+	if v := p.ValueTimeout(0, "timeout"); v != 7 {
+		t.Errorf("delivered ValueTimeout(0) => %v, want 7", v)
+	}
+}
+
+func TestFuture(t *testing.T) {
+	start := make(chan bool)
+	f := NewFuture(func(ctx context.Context) (interface{}, error) {
+		<-start
+		return "done", nil
+	})
+	if v := f.ValueTimeout(10*time.Millisecond, "timeout"); v != "timeout" {
+		t.Errorf("unfinished ValueTimeout => %v, want timeout", v)
+	}
+	close(start)
+	if v, err := f.Result(); v != "done" || err != nil {
+		t.Errorf("Result() => %v, %v; want done, nil", v, err)
+	}
+	if f.Cancel() {
+		t.Error("Cancel after finishing => true")
+	}
+}
+
+func TestFutureCancel(t *testing.T) {
+	f := NewFuture(func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if !f.Cancel() {
+		t.Error("Cancel of running Future => false")
+	}
+	if _, err := f.Result(); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Result error = %v, want context.Canceled", err)
+	}
+
+	g := NewFuture(func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return 1, nil
+	})
+	g.Cancel()
+	if _, err := g.Result(); err != ErrCancelled {
+		t.Errorf("cancelled Result error = %v, want ErrCancelled", err)
+	}
+	defer func() {
+		if r := recover(); r != ErrCancelled {
+			t.Errorf("Value of cancelled Future panicked with %v, want ErrCancelled", r)
+		}
+	}()
+	g.Value()
+}
@@ -0,0 +1,201 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Diagnostics
+//
+// Each Run keeps a log of why its attempts were retried.  If it gives up,
+// the RetryLimitError carries the most recent records and a count of retries by Ref,
+// which usually points straight at the hotspot.
+// Ref.Retries counts retries caused by a Ref across all transactions.
+// DumpLocks lists the transactions currently holding Refs.
+
+// MaxRetryRecords is the number of retry records kept by a transaction.
+const MaxRetryRecords = 32
+
+// A RetryRecord describes one retried attempt.
+type RetryRecord struct {
+	// The attempt that was retried, starting at 0
+	Attempt int
+
+	Reason RetryReason
+
+	// The Ref involved, if known
+	Ref *Ref
+
+	// The transaction holding the Ref, if known
+	Holder *TxInfo
+
+	// Its start point
+	HolderStart uint64
+}
+
+func (r RetryRecord) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "attempt %d: %v", r.Attempt, r.Reason)
+	if r.Ref != nil {
+		fmt.Fprintf(&b, " on ref %d", r.Ref.id)
+	}
+	if r.Holder != nil {
+		fmt.Fprintf(&b, " held by tx started at point %d", r.HolderStart)
+	}
+	return b.String()
+}
+
+// A Hotspot counts the retries a transaction suffered on one Ref.
+type Hotspot struct {
+	Ref     *Ref
+	Retries int
+}
+
+type retryLog struct {
+	recent  []RetryRecord // ring buffer of the last MaxRetryRecords
+	next    int
+	byRef   map[*Ref]int
+	reasons map[RetryReason]int
+}
+
+func (tx *Tx) recordRetry(attempt int, rs *retrySignal) {
+	rec := RetryRecord{Attempt: attempt, Reason: rs.reason, Ref: rs.ref, Holder: rs.holder}
+	if rs.holder != nil {
+		rec.HolderStart = rs.holder.startPoint
+	}
+	log := &tx.retryLog
+	if len(log.recent) < MaxRetryRecords {
+		log.recent = append(log.recent, rec)
+	} else {
+		log.recent[log.next] = rec
+	}
+	log.next = (log.next + 1) % MaxRetryRecords
+	if log.reasons == nil {
+		log.reasons = make(map[RetryReason]int)
+	}
+	log.reasons[rs.reason]++
+	if rs.ref != nil {
+		if log.byRef == nil {
+			log.byRef = make(map[*Ref]int)
+		}
+		log.byRef[rs.ref]++
+		atomic.AddUint64(&rs.ref.retries, 1)
+	}
+}
+
+func (tx *Tx) retryLimitError(attempts int) *RetryLimitError {
+	log := &tx.retryLog
+	e := &RetryLimitError{Attempts: attempts, Reasons: log.reasons}
+
+	// oldest first
+	if len(log.recent) < MaxRetryRecords {
+		e.Recent = append(e.Recent, log.recent...)
+	} else {
+		e.Recent = append(e.Recent, log.recent[log.next:]...)
+		e.Recent = append(e.Recent, log.recent[:log.next]...)
+	}
+
+	for _, r := range sortedRefs(log.byRef) {
+		e.Hotspots = append(e.Hotspots, Hotspot{Ref: r, Retries: log.byRef[r]})
+	}
+	sort.SliceStable(e.Hotspots, func(i, j int) bool { return e.Hotspots[i].Retries > e.Hotspots[j].Retries })
+	return e
+}
+
+// StartPoint returns the point at which the transaction started.
+// Older transactions (lower start points) win conflicts.
+func (info *TxInfo) StartPoint() uint64 {
+	return info.startPoint
+}
+
+// Status returns the status of the transaction: running, committing, retry, killed, or committed.
+func (info *TxInfo) Status() string {
+	switch atomic.LoadUint32(&info.status) {
+	case txRunning:
+		return "running"
+	case txCommitting:
+		return "committing"
+	case txRetry:
+		return "retry"
+	case txKilled:
+		return "killed"
+	case txCommitted:
+		return "committed"
+	}
+	return "unknown"
+}
+
+// Current holders.
+// lockHolders maps a Ref to the transaction that has claimed it for writing;
+// ensureHolders maps a (Ref, Tx) pair to the TxInfo of a transaction that has ensured the Ref.
+var (
+	lockHolders   sync.Map
+	ensureHolders sync.Map
+)
+
+type holdKey struct {
+	ref *Ref
+	tx  *Tx
+}
+
+// A LockHolder describes a transaction holding a Ref.
+type LockHolder struct {
+	Ref    *Ref
+	Holder *TxInfo
+
+	// True if the Ref is ensured (read-locked) rather than claimed for writing
+	Ensure bool
+}
+
+// LockHolders returns the Refs currently claimed or ensured by running transactions, ordered by Ref.
+func LockHolders() []LockHolder {
+	var holders []LockHolder
+	lockHolders.Range(func(k, v interface{}) bool {
+		if info := v.(*TxInfo); info.isRunning() {
+			holders = append(holders, LockHolder{Ref: k.(*Ref), Holder: info})
+		}
+		return true
+	})
+	ensureHolders.Range(func(k, v interface{}) bool {
+		if info := v.(*TxInfo); info.isRunning() {
+			holders = append(holders, LockHolder{Ref: k.(holdKey).ref, Holder: info, Ensure: true})
+		}
+		return true
+	})
+	sort.Slice(holders, func(i, j int) bool {
+		a, b := holders[i], holders[j]
+		if a.Ref.id != b.Ref.id {
+			return a.Ref.id < b.Ref.id
+		}
+		if a.Ensure != b.Ensure {
+			return !a.Ensure
+		}
+		return a.Holder.startPoint < b.Holder.startPoint
+	})
+	return holders
+}
+
+// DumpLocks writes a line for each Ref held by a running transaction,
+// with the holder's start point and status and the Ref's retry count.
+func DumpLocks(w io.Writer) error {
+	for _, h := range LockHolders() {
+		how := "claimed"
+		if h.Ensure {
+			how = "ensured"
+		}
+		_, err := fmt.Fprintf(w, "ref %d: %s by tx started at point %d (%s), %d retries\n",
+			h.Ref.id, how, h.Holder.startPoint, h.Holder.Status(), h.Ref.Retries())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
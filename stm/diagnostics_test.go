@@ -0,0 +1,136 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryDiagnostics(t *testing.T) {
+	r := NewRef(0)
+	holding := make(chan bool)
+	release := make(chan bool)
+	done := make(chan bool)
+
+	// An older transaction claims r and sits on it
+	go func() {
+		RunInTransaction(func(tx *Tx) interface{} {
+			r.Set(tx, 1)
+			select {
+			case holding <- true:
+			default:
+			}
+			<-release
+			return nil
+		})
+		close(done)
+	}()
+	<-holding
+
+	holders := LockHolders()
+	var holder *TxInfo
+	for _, h := range holders {
+		if h.Ref == r && !h.Ensure {
+			holder = h.Holder
+		}
+	}
+	if holder == nil {
+		t.Fatalf("LockHolders() did not report the holder of ref %d: %v", r.ID(), holders)
+	}
+
+	var buf bytes.Buffer
+	if err := DumpLocks(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("ref %d: claimed by tx started at point %d (running)", r.ID(), holder.StartPoint())
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("DumpLocks output %q does not contain %q", buf.String(), want)
+	}
+
+	before := r.Retries()
+	_, err := RunInTransactionWithOptions(TxOptions{RetryLimit: 3, LockWait: time.Millisecond}, func(tx *Tx) interface{} {
+		return r.Set(tx, 2)
+	})
+	close(release)
+	<-done
+
+	var rle *RetryLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("Expected a *RetryLimitError, got %v", err)
+	}
+	if len(rle.Recent) != 3 {
+		t.Fatalf("Expected 3 retry records, got %d", len(rle.Recent))
+	}
+	for i, rec := range rle.Recent {
+		if rec.Attempt != i || rec.Reason != RetryBargeLost || rec.Ref != r || rec.Holder != holder || rec.HolderStart != holder.StartPoint() {
+			t.Errorf("%d. retry record %v, want barge lost on ref %d held by tx started at point %d", i, rec, r.ID(), holder.StartPoint())
+		}
+	}
+	if len(rle.Hotspots) != 1 || rle.Hotspots[0].Ref != r || rle.Hotspots[0].Retries != 3 {
+		t.Errorf("Hotspots = %v, want ref %d x3", rle.Hotspots, r.ID())
+	}
+	if rle.Reasons[RetryBargeLost] != 3 {
+		t.Errorf("Reasons = %v, want barge lost x3", rle.Reasons)
+	}
+	if msg := err.Error(); !strings.Contains(msg, fmt.Sprintf("ref %d x3", r.ID())) || !strings.Contains(msg, "barge lost x3") {
+		t.Errorf("error message %q lacks hotspot and reason", msg)
+	}
+	if n := r.Retries() - before; n != 3 {
+		t.Errorf("Ref.Retries() increased by %d, want 3", n)
+	}
+
+	for _, h := range LockHolders() {
+		if h.Ref == r {
+			t.Errorf("ref %d still reported held after its transactions finished", r.ID())
+		}
+	}
+}
+
+func TestRetryRecordsBounded(t *testing.T) {
+	n := MaxRetryRecords + 10
+	_, err := RunInTransactionWithOptions(TxOptions{RetryLimit: n}, func(tx *Tx) interface{} {
+		panic(retryError)
+	})
+	var rle *RetryLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("Expected a *RetryLimitError, got %v", err)
+	}
+	if len(rle.Recent) != MaxRetryRecords {
+		t.Fatalf("Expected %d retry records, got %d", MaxRetryRecords, len(rle.Recent))
+	}
+	if first, last := rle.Recent[0].Attempt, rle.Recent[MaxRetryRecords-1].Attempt; first != 10 || last != n-1 {
+		t.Errorf("retry records cover attempts %d..%d, want 10..%d", first, last, n-1)
+	}
+	if rle.Reasons[RetryUnknown] != n {
+		t.Errorf("Reasons = %v, want unknown x%d", rle.Reasons, n)
+	}
+}
+
+func TestEnsureHolders(t *testing.T) {
+	r := NewRef(0)
+	RunInTransaction(func(tx *Tx) interface{} {
+		r.Touch(tx)
+		found := false
+		for _, h := range LockHolders() {
+			if h.Ref == r && h.Ensure {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("LockHolders() did not report ensure of ref %d", r.ID())
+		}
+		return nil
+	})
+	for _, h := range LockHolders() {
+		if h.Ref == r {
+			t.Errorf("ref %d still reported held after commit", r.ID())
+		}
+	}
+}
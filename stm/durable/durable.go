@@ -0,0 +1,329 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package durable persists the values of named Refs across process restarts.
+//
+// A Store keeps two files in a directory: a write-ahead log, to which each transaction's
+// writes to the Store's Refs are appended with their commit point,
+// and a snapshot holding one value per name.
+// Recovery loads the snapshot and replays the log over it.
+// Compact writes a new snapshot and starts an empty log.
+//
+// Log entries are appended through an stm.CommitLog on each of the Store's Refs:
+// after the transaction has locked the Refs, and before their new values are visible.
+// A commit that readers can see is therefore in the log, and entries for any one Ref
+// appear in the log in commit order.  If the append fails, the transaction is not committed;
+// Tx.Run returns an error satisfying errors.Is(err, stm.ErrCommitLog).
+// Commit points are recorded but not needed for replay; they are not comparable across processes.
+package durable
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dmiller/go-seq/stm"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	logName      = "wal"
+	snapshotName = "snapshot"
+)
+
+var errClosed = errors.New("durable: store is closed")
+
+// Options configure a Store.
+type Options struct {
+	// Codec for Ref values (default GobCodec)
+	Codec Codec
+
+	// Sync the log to stable storage after each append
+	Sync bool
+}
+
+// A Store logs commits to its named Refs.
+type Store struct {
+	dir   string
+	codec Codec
+	sync  bool
+
+	mu        sync.Mutex
+	log       *os.File
+	names     map[*stm.Ref]string
+	refs      map[string]*stm.Ref
+	values    map[string]interface{} // latest logged value for each name
+	point     uint64                 // latest logged commit point
+	err       error                  // first failure to write the log
+	commitLog *stm.CommitLog         // attached to each of the Store's Refs
+}
+
+// Open opens (creating if necessary) the Store in directory dir, recovering its state.
+func Open(dir string, opts Options) (*Store, error) {
+	s := &Store{
+		dir:   dir,
+		codec: opts.Codec,
+		sync:  opts.Sync,
+		names: make(map[*stm.Ref]string),
+		refs:  make(map[string]*stm.Ref),
+	}
+	if s.codec == nil {
+		s.codec = GobCodec
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	values, point, valid, err := recoverValues(dir, s.codec)
+	if err != nil {
+		return nil, err
+	}
+	s.values, s.point = values, point
+
+	s.log, err = os.OpenFile(filepath.Join(dir, logName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// Drop a torn tail so that new records follow the last good one.
+	// Corruption before the tail has already failed recovery, so only an interrupted write is lost.
+	if err := s.log.Truncate(valid); err != nil {
+		s.log.Close()
+		return nil, err
+	}
+	s.commitLog = stm.NewCommitLog(s.logCommit)
+	return s, nil
+}
+
+// Recover rebuilds the named Refs stored in directory dir from the snapshot and log.
+// The Refs are not attached to a Store; use Open and Store.Ref to keep logging them.
+func Recover(dir string, codec Codec) (map[string]*stm.Ref, error) {
+	if codec == nil {
+		codec = GobCodec
+	}
+	values, _, _, err := recoverValues(dir, codec)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]*stm.Ref, len(values))
+	for name, v := range values {
+		refs[name] = stm.NewRef(v)
+	}
+	return refs, nil
+}
+
+// Returns the recovered values, the latest point, and the length of the valid part of the log.
+func recoverValues(dir string, codec Codec) (map[string]interface{}, uint64, int64, error) {
+	values := make(map[string]interface{})
+	var point uint64
+	apply := func(rec *record) error {
+		for _, e := range rec.entries {
+			v, err := codec.Unmarshal(e.data)
+			if err != nil {
+				return fmt.Errorf("durable: decoding %q: %v", e.name, err)
+			}
+			values[e.name] = v
+		}
+		if rec.point > point {
+			point = rec.point
+		}
+		return nil
+	}
+
+	for _, name := range []string{snapshotName, logName} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, 0, err
+		}
+		valid, err := readRecords(f, fi.Size(), apply)
+		f.Close()
+		if errors.Is(err, ErrCorrupt) {
+			return nil, 0, 0, fmt.Errorf("%w in %s", err, name)
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if name == logName {
+			return values, point, valid, nil
+		}
+	}
+	return values, point, 0, nil
+}
+
+// Ref returns the Ref stored under name.
+// If the Store recovered a value for name, the Ref starts with that value;
+// otherwise it starts with init, which is logged.
+// Calling Ref again with the same name returns the same Ref.
+func (s *Store) Ref(name string, init interface{}) (*stm.Ref, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.refs[name]; ok {
+		return r, nil
+	}
+	v, ok := s.values[name]
+	if !ok {
+		rec := &record{point: stm.CurrentPoint()}
+		if err := s.addEntry(rec, name, init); err != nil {
+			return nil, err
+		}
+		if err := s.append(rec); err != nil {
+			return nil, err
+		}
+		s.values[name] = init
+		v = init
+	}
+	r := stm.NewRef(v)
+	r.SetCommitLog(s.commitLog)
+	s.refs[name] = r
+	s.names[r] = name
+	return r, nil
+}
+
+// Append a transaction's writes to the Store's Refs to the log, before they are committed.
+// Returning an error keeps the transaction from committing.
+// A value that cannot be encoded fails only its transaction;
+// a failure to write the log is kept, returned by Err, and fails every later commit.
+func (s *Store) logCommit(point uint64, writes []stm.RefWrite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if s.log == nil {
+		return errClosed
+	}
+	rec := &record{point: point}
+	for _, w := range writes {
+		if err := s.addEntry(rec, s.names[w.Ref], w.New); err != nil {
+			return err
+		}
+	}
+	if s.err = s.append(rec); s.err != nil {
+		return s.err
+	}
+	for _, w := range writes {
+		s.values[s.names[w.Ref]] = w.New
+	}
+	return nil
+}
+
+func (s *Store) addEntry(rec *record, name string, v interface{}) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("durable: encoding %q: %v", name, err)
+	}
+	rec.entries = append(rec.entries, entry{name: name, data: data})
+	return nil
+}
+
+// Append a record to the log.  Caller holds s.mu.
+func (s *Store) append(rec *record) error {
+	if _, err := s.log.Write(rec.encode()); err != nil {
+		return err
+	}
+	if rec.point > s.point {
+		s.point = rec.point
+	}
+	if s.sync {
+		return s.log.Sync()
+	}
+	return nil
+}
+
+// Err returns the first error encountered writing the log, if any.
+// After an error, commits to the Store's Refs fail.
+func (s *Store) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Compact writes a snapshot of the latest logged values and empties the log.
+// Commits to the Store's Refs wait while it runs.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+
+	rec := &record{point: s.point}
+	for name, v := range s.values {
+		if err := s.addEntry(rec, name, v); err != nil {
+			return err
+		}
+	}
+	if err := writeFile(filepath.Join(s.dir, snapshotName), rec.encode()); err != nil {
+		return err
+	}
+
+	// Every record in the old log is reflected in the snapshot,
+	// so a crash before the new log replaces it only replays redundant writes.
+	if err := writeFile(filepath.Join(s.dir, logName), nil); err != nil {
+		return err
+	}
+	log, err := os.OpenFile(filepath.Join(s.dir, logName), os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		s.err = err
+		return err
+	}
+	s.log.Close()
+	s.log = log
+	return nil
+}
+
+// Replace the file at path with data, atomically
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Close stops logging and closes the log.
+// The Store's Refs are detached from it and can still be used, but are no longer logged.
+// Returns the first error encountered writing the log, if any.
+func (s *Store) Close() error {
+	// Not under s.mu:  a commit holding a Ref's lock may be waiting for s.mu to log
+	s.mu.Lock()
+	refs := make([]*stm.Ref, 0, len(s.refs))
+	for _, r := range s.refs {
+		refs = append(refs, r)
+	}
+	s.mu.Unlock()
+	for _, r := range refs {
+		r.SetCommitLog(nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return s.err
+	}
+	err := s.log.Close()
+	s.log = nil
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
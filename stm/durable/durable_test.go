@@ -0,0 +1,300 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package durable
+
+import (
+	"errors"
+	"github.com/dmiller/go-seq/stm"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func add(v interface{}, args ...interface{}) interface{} {
+	return v.(int) + args[0].(int)
+}
+
+func openStore(t *testing.T, dir string, opts Options) *Store {
+	s, err := Open(dir, opts)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return s
+}
+
+func storeRef(t *testing.T, s *Store, name string, init interface{}) *stm.Ref {
+	r, err := s.Ref(name, init)
+	if err != nil {
+		t.Fatalf("Ref(%q): %v", name, err)
+	}
+	return r
+}
+
+func TestRecoverFromLog(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{})
+	a := storeRef(t, s, "a", 0)
+	b := storeRef(t, s, "b", "x")
+	other := stm.NewRef(0)
+
+	for i := 0; i < 10; i++ {
+		stm.RunInTransaction(func(tx *stm.Tx) interface{} {
+			a.Alter(tx, add, 1)
+			other.Alter(tx, add, 1)
+			return nil
+		})
+	}
+	stm.RunInTransaction(func(tx *stm.Tx) interface{} { return b.Set(tx, "y") })
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	refs, err := Recover(dir, nil)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(refs) != 2 {
+		t.Errorf("Recover found %d refs, want 2", len(refs))
+	}
+	if v := refs["a"].Deref(nil); v != 10 {
+		t.Errorf("recovered a = %v, want 10", v)
+	}
+	if v := refs["b"].Deref(nil); v != "y" {
+		t.Errorf("recovered b = %v, want y", v)
+	}
+
+	s = openStore(t, dir, Options{})
+	defer s.Close()
+	a = storeRef(t, s, "a", 0)
+	if v := a.Deref(nil); v != 10 {
+		t.Errorf("reopened a = %v, want 10", v)
+	}
+	stm.RunInTransaction(func(tx *stm.Tx) interface{} { return a.Alter(tx, add, 5) })
+	refs, _ = Recover(dir, nil)
+	if v := refs["a"].Deref(nil); v != 15 {
+		t.Errorf("recovered a after reopening = %v, want 15", v)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{Sync: true})
+	defer s.Close()
+	a := storeRef(t, s, "a", 0)
+	for i := 0; i < 20; i++ {
+		stm.RunInTransaction(func(tx *stm.Tx) interface{} { return a.Alter(tx, add, 1) })
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, logName)); err != nil || fi.Size() != 0 {
+		t.Errorf("log after Compact: %v, %v; want empty", fi, err)
+	}
+	stm.RunInTransaction(func(tx *stm.Tx) interface{} { return a.Alter(tx, add, 1) })
+
+	refs, err := Recover(dir, nil)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if v := refs["a"].Deref(nil); v != 21 {
+		t.Errorf("recovered a = %v, want 21", v)
+	}
+}
+
+func TestTornTail(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{})
+	a := storeRef(t, s, "a", 0)
+	for i := 0; i < 3; i++ {
+		stm.RunInTransaction(func(tx *stm.Tx) interface{} { return a.Alter(tx, add, 1) })
+	}
+	s.Close()
+
+	path := filepath.Join(dir, logName)
+	fi, _ := os.Stat(path)
+	if err := os.Truncate(path, fi.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, dir, Options{})
+	defer s.Close()
+	a = storeRef(t, s, "a", 0)
+	if v := a.Deref(nil); v != 2 {
+		t.Errorf("a after torn tail = %v, want 2", v)
+	}
+	stm.RunInTransaction(func(tx *stm.Tx) interface{} { return a.Alter(tx, add, 10) })
+	refs, _ := Recover(dir, nil)
+	if v := refs["a"].Deref(nil); v != 12 {
+		t.Errorf("recovered a = %v, want 12", v)
+	}
+}
+
+func TestGarbageLengthTail(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{})
+	a := storeRef(t, s, "a", 0)
+	for i := 0; i < 3; i++ {
+		stm.RunInTransaction(func(tx *stm.Tx) interface{} { return a.Alter(tx, add, 1) })
+	}
+	s.Close()
+
+	path := filepath.Join(dir, logName)
+	fi, _ := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0xf0, 0xff, 0xff, 0xff, 1, 2, 3, 4, 5})
+	f.Close()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	s = openStore(t, dir, Options{})
+	runtime.ReadMemStats(&after)
+	defer s.Close()
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("Open allocated %d bytes for a garbage record length", n)
+	}
+	a = storeRef(t, s, "a", 0)
+	if v := a.Deref(nil); v != 3 {
+		t.Errorf("a after garbage tail = %v, want 3", v)
+	}
+	if fi2, err := os.Stat(path); err != nil || fi2.Size() != fi.Size() {
+		t.Errorf("log after Open: %v, %v; want %d bytes", fi2, err, fi.Size())
+	}
+}
+
+func TestCorruptRecord(t *testing.T) {
+	var tests = []struct {
+		last bool // corrupt the last record rather than the first
+		err  error
+		a    int
+	}{
+		{false, ErrCorrupt, 0},
+		{true, nil, 2},
+	}
+
+	for i, tt := range tests {
+		dir := t.TempDir()
+		s := openStore(t, dir, Options{})
+		a := storeRef(t, s, "a", 0)
+		for i := 0; i < 3; i++ {
+			stm.RunInTransaction(func(tx *stm.Tx) interface{} { return a.Alter(tx, add, 1) })
+		}
+		s.Close()
+
+		path := filepath.Join(dir, logName)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.last {
+			data[len(data)-1] ^= 0xff
+		} else {
+			data[headerSize] ^= 0xff
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Recover(dir, nil); !errors.Is(err, tt.err) {
+			t.Errorf("%d. Recover => %v, want %v", i, err, tt.err)
+		}
+		s, err = Open(dir, Options{})
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%d. Open => %v, want %v", i, err, tt.err)
+		}
+		if err != nil {
+			if fi, err := os.Stat(path); err != nil || fi.Size() != int64(len(data)) {
+				t.Errorf("%d. log after failed Open: %v, %v; want %d bytes", i, fi, err, len(data))
+			}
+			continue
+		}
+		a = storeRef(t, s, "a", 0)
+		if v := a.Deref(nil); v != tt.a {
+			t.Errorf("%d. a after corrupt record = %v, want %v", i, v, tt.a)
+		}
+		s.Close()
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{Codec: JSONCodec})
+	m := storeRef(t, s, "m", map[string]interface{}{"k": "v"})
+	stm.RunInTransaction(func(tx *stm.Tx) interface{} {
+		return m.Set(tx, map[string]interface{}{"k": 2})
+	})
+	s.Close()
+
+	refs, err := Recover(dir, JSONCodec)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	got := refs["m"].Deref(nil).(map[string]interface{})
+	if got["k"] != 2.0 {
+		t.Errorf("recovered m = %v, want map[k:2]", got)
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{Codec: JSONCodec})
+	defer s.Close()
+	r := storeRef(t, s, "f", 0)
+
+	_, err := stm.RunInTransaction(func(tx *stm.Tx) interface{} { return r.Set(tx, func() {}) })
+	if !errors.Is(err, stm.ErrCommitLog) {
+		t.Errorf("committing an unencodable value: err = %v, want ErrCommitLog", err)
+	}
+	if v := r.Deref(nil); v != 0 {
+		t.Errorf("r = %v after a failed commit, want 0", v)
+	}
+
+	// only that transaction fails
+	if s.Err() != nil {
+		t.Errorf("Err() = %v after an unencodable value", s.Err())
+	}
+	if _, err := stm.RunInTransaction(func(tx *stm.Tx) interface{} { return r.Set(tx, 1) }); err != nil {
+		t.Errorf("committing after an unencodable value: %v", err)
+	}
+}
+
+func TestWriteAhead(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{})
+	r := storeRef(t, s, "a", 0)
+	other := stm.NewRef(0)
+
+	// A commit is not visible unless it was logged
+	s.log.Close()
+	_, err := stm.RunInTransaction(func(tx *stm.Tx) interface{} {
+		other.Set(tx, 1)
+		return r.Set(tx, 1)
+	})
+	if !errors.Is(err, stm.ErrCommitLog) {
+		t.Errorf("committing with a broken log: err = %v, want ErrCommitLog", err)
+	}
+	if v, w := r.Deref(nil), other.Deref(nil); v != 0 || w != 0 {
+		t.Errorf("refs = %v, %v after a failed log write, want 0, 0", v, w)
+	}
+	if s.Err() == nil {
+		t.Errorf("Err() = nil after a failed log write")
+	}
+
+	// Refs not in the Store are unaffected
+	if _, err := stm.RunInTransaction(func(tx *stm.Tx) interface{} { return other.Set(tx, 2) }); err != nil {
+		t.Errorf("committing to another ref: %v", err)
+	}
+
+	// Closing detaches the Store's Refs
+	if s.Close() == nil {
+		t.Errorf("Close() = nil after a failed log write")
+	}
+	if _, err := stm.RunInTransaction(func(tx *stm.Tx) interface{} { return r.Set(tx, 3) }); err != nil {
+		t.Errorf("committing after Close: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package durable
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// The log and the snapshot are sequences of records.
+// Each record is framed as
+//
+//	length  uint32, little-endian, of the payload
+//	crc     uint32, little-endian, CRC-32 (Castagnoli) of the payload
+//	payload uvarint point, uvarint count, then count entries of
+//	        uvarint len, name, uvarint len, encoded value
+//
+// A record that is cut short, whose length runs past the end of the file,
+// or that fails its checksum as the last record in the file ends the sequence:
+// it is the tail of a write interrupted by a crash.
+// A bad record with more data after it is not a torn write but damage to
+// committed records; reading stops with ErrCorrupt rather than dropping the rest.
+
+const headerSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errBadRecord = errors.New("durable: malformed record")
+
+// ErrCorrupt is returned by Open and Recover when a record before the end of
+// the snapshot or log fails its checksum or cannot be decoded.
+var ErrCorrupt = errors.New("durable: corrupt record")
+
+// A record is the set of named values written at a commit point
+type record struct {
+	point   uint64
+	entries []entry
+}
+
+type entry struct {
+	name string
+	data []byte
+}
+
+func (rec *record) encode() []byte {
+	payload := binary.AppendUvarint(nil, rec.point)
+	payload = binary.AppendUvarint(payload, uint64(len(rec.entries)))
+	for _, e := range rec.entries {
+		payload = binary.AppendUvarint(payload, uint64(len(e.name)))
+		payload = append(payload, e.name...)
+		payload = binary.AppendUvarint(payload, uint64(len(e.data)))
+		payload = append(payload, e.data...)
+	}
+	buf := make([]byte, headerSize, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:], crc32.Checksum(payload, crcTable))
+	return append(buf, payload...)
+}
+
+func decodePayload(p []byte) (*record, error) {
+	rec := &record{}
+	var n int
+	if rec.point, n = binary.Uvarint(p); n <= 0 {
+		return nil, errBadRecord
+	}
+	p = p[n:]
+	count, n := binary.Uvarint(p)
+	if n <= 0 {
+		return nil, errBadRecord
+	}
+	p = p[n:]
+	for i := uint64(0); i < count; i++ {
+		name, rest, err := readBytes(p)
+		if err != nil {
+			return nil, err
+		}
+		data, rest, err := readBytes(rest)
+		if err != nil {
+			return nil, err
+		}
+		rec.entries = append(rec.entries, entry{name: string(name), data: data})
+		p = rest
+	}
+	return rec, nil
+}
+
+func readBytes(p []byte) ([]byte, []byte, error) {
+	l, n := binary.Uvarint(p)
+	if n <= 0 || uint64(len(p)-n) < l {
+		return nil, nil, errBadRecord
+	}
+	p = p[n:]
+	return p[:l], p[l:], nil
+}
+
+// Read records from r, which holds size bytes, calling fn on each.
+// Returns the number of bytes in the valid records read.
+func readRecords(r io.Reader, size int64, fn func(*record) error) (int64, error) {
+	var valid int64
+	hdr := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return valid, nil
+			}
+			return valid, err
+		}
+		// The length is not covered by the checksum; don't allocate more than the file holds
+		length := int64(binary.LittleEndian.Uint32(hdr[0:]))
+		end := valid + headerSize + length
+		if end > size {
+			return valid, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return valid, nil
+			}
+			return valid, err
+		}
+		var rec *record
+		err := errBadRecord
+		if crc32.Checksum(payload, crcTable) == binary.LittleEndian.Uint32(hdr[4:]) {
+			rec, err = decodePayload(payload)
+		}
+		if err != nil {
+			if end == size {
+				return valid, nil
+			}
+			return valid, fmt.Errorf("%w at offset %d", ErrCorrupt, valid)
+		}
+		if err := fn(rec); err != nil {
+			return valid, err
+		}
+		valid = end
+	}
+}
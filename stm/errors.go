@@ -0,0 +1,172 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors reported by transactions.
+// Tx.Run returns these (possibly wrapped in one of the typed errors below) rather than panicking.
+// Use errors.Is to test for them.
+var (
+	// ErrRetryLimit indicates a transaction gave up after too many conflicts.
+	ErrRetryLimit = errors.New("Transaction failed after reaching retry limit")
+
+	// ErrAborted indicates the transaction body called Tx.Abort.
+	ErrAborted = errors.New("Transaction aborted")
+
+	// ErrSetAfterCommute indicates a Set or Alter on a Ref already commuted in the same transaction.
+	ErrSetAfterCommute = errors.New("Can't set after commute")
+
+	// ErrValidation indicates the transaction body rejected the state of a Ref.
+	// A body panics with ErrValidation (or a *ValidationError) to end the transaction without committing.
+	ErrValidation = errors.New("Invalid reference state")
+
+	// ErrCommitLog indicates a Ref's CommitLog failed, so the transaction was not committed.
+	ErrCommitLog = errors.New("Commit log failed")
+
+	// ErrReadOnly indicates an attempt to change a Ref in a read-only transaction.
+	ErrReadOnly = errors.New("Can't change a ref in a read-only transaction")
+
+	// ErrIOInTransaction indicates an attempt to perform I/O inside a transaction.
+	ErrIOInTransaction = errors.New("I/O in transaction")
+
+	// ErrNotInTransaction indicates a transactional operation was attempted without a running transaction.
+	ErrNotInTransaction = errors.New("No transaction running")
+)
+
+// A RetryLimitError is returned by Tx.Run when the transaction cannot commit within its retry limit.
+type RetryLimitError struct {
+	// Number of attempts made
+	Attempts int
+
+	// The last (up to MaxRetryRecords) retries, oldest first
+	Recent []RetryRecord
+
+	// Retries by Ref, most first
+	Hotspots []Hotspot
+
+	// Retries by reason
+	Reasons map[RetryReason]int
+}
+
+func (e *RetryLimitError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v (%d attempts", ErrRetryLimit, e.Attempts)
+	for i, h := range e.Hotspots {
+		if i == 3 {
+			break
+		}
+		sep := ", "
+		if i == 0 {
+			sep = "; hotspots: "
+		}
+		fmt.Fprintf(&b, "%sref %d x%d", sep, h.Ref.id, h.Retries)
+	}
+	first := true
+	for reason := RetryUnknown; reason <= RetryKilled; reason++ {
+		if n := e.Reasons[reason]; n > 0 {
+			sep := ", "
+			if first {
+				sep, first = "; reasons: ", false
+			}
+			fmt.Fprintf(&b, "%s%v x%d", sep, reason, n)
+		}
+	}
+	if n := len(e.Recent); n > 0 {
+		fmt.Fprintf(&b, "; last: %v", e.Recent[n-1])
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// Unwrap returns ErrRetryLimit
+func (e *RetryLimitError) Unwrap() error {
+	return ErrRetryLimit
+}
+
+// A SetAfterCommuteError identifies the Ref that was set after being commuted.
+type SetAfterCommuteError struct {
+	Ref *Ref
+}
+
+func (e *SetAfterCommuteError) Error() string {
+	return fmt.Sprintf("%v (ref %d)", ErrSetAfterCommute, e.Ref.id)
+}
+
+// Unwrap returns ErrSetAfterCommute
+func (e *SetAfterCommuteError) Unwrap() error {
+	return ErrSetAfterCommute
+}
+
+// A ReadOnlyError identifies the Ref a read-only transaction tried to change.
+type ReadOnlyError struct {
+	Ref *Ref
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%v (ref %d)", ErrReadOnly, e.Ref.id)
+}
+
+// Unwrap returns ErrReadOnly
+func (e *ReadOnlyError) Unwrap() error {
+	return ErrReadOnly
+}
+
+// A ValidationError identifies the Ref and the value a transaction body rejected.
+type ValidationError struct {
+	Ref *Ref
+	Val interface{}
+
+	// Why the value was rejected
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v (ref %d, value %v): %v", ErrValidation, e.Ref.id, e.Val, e.Err)
+}
+
+// Is reports whether target is ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Unwrap returns the reason the value was rejected
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// A CommitLogError holds the error returned by a CommitLog.
+type CommitLogError struct {
+	Err error
+}
+
+func (e *CommitLogError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrCommitLog, e.Err)
+}
+
+// Is reports whether target is ErrCommitLog
+func (e *CommitLogError) Is(target error) bool {
+	return target == ErrCommitLog
+}
+
+// Unwrap returns the error returned by the CommitLog
+func (e *CommitLogError) Unwrap() error {
+	return e.Err
+}
+
+// isTxError returns true if the error is one that should end a transaction and be returned from Tx.Run
+func isTxError(err error) bool {
+	return errors.Is(err, ErrAborted) ||
+		errors.Is(err, ErrSetAfterCommute) ||
+		errors.Is(err, ErrValidation) ||
+		errors.Is(err, ErrCommitLog) ||
+		errors.Is(err, ErrReadOnly) ||
+		errors.Is(err, ErrIOInTransaction) ||
+		errors.Is(err, ErrNotInTransaction)
+}
@@ -0,0 +1,78 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"testing"
+)
+
+var errTooBig = errors.New("too big")
+
+func TestRetryLimitError(t *testing.T) {
+	f := func(tx *Tx) interface{} {
+		panic(retryError)
+	}
+
+	_, e := RunInTransactionWithOptions(TxOptions{RetryLimit: 2}, f)
+
+	if !errors.Is(e, ErrRetryLimit) {
+		t.Errorf("Expected ErrRetryLimit, got %v", e)
+	}
+
+	var rle *RetryLimitError
+	if !errors.As(e, &rle) {
+		t.Fatalf("Expected a *RetryLimitError, got %T", e)
+	}
+	if rle.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", rle.Attempts)
+	}
+}
+
+func TestValidationErrorEndsTransaction(t *testing.T) {
+	r1 := NewRef(10)
+
+	_, e := RunInTransaction(func(tx *Tx) interface{} {
+		r1.Set(tx, 200)
+		if v := r1.Deref(tx).(int); v > 100 {
+			panic(&ValidationError{Ref: r1, Val: v, Err: errTooBig})
+		}
+		return nil
+	})
+
+	if !errors.Is(e, ErrValidation) {
+		t.Errorf("Expected ErrValidation, got %v", e)
+	}
+
+	if !errors.Is(e, errTooBig) {
+		t.Errorf("Expected the reason to be wrapped, got %v", e)
+	}
+
+	var ve *ValidationError
+	if !errors.As(e, &ve) {
+		t.Fatalf("Expected a *ValidationError, got %T", e)
+	}
+	if ve.Ref != r1 || ve.Val != 200 {
+		t.Errorf("Expected the error to identify r1 and 200, got %v and %v", ve.Ref, ve.Val)
+	}
+
+	if v := r1.Deref(nil); v != 10 {
+		t.Errorf("Expected r1 to have value 10, got %v", v)
+	}
+}
+
+func TestNotInTransaction(t *testing.T) {
+	r1 := NewRef(1)
+
+	defer func() {
+		r := recover()
+		if e, ok := r.(error); !ok || !errors.Is(e, ErrNotInTransaction) {
+			t.Errorf("Expected a panic with ErrNotInTransaction, got %v", r)
+		}
+	}()
+
+	r1.Set(nil, 2)
+	t.Errorf("Set without a transaction should panic")
+}
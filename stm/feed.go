@@ -0,0 +1,232 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A CommitEvent describes a commit that wrote subscribed Refs.
+type CommitEvent struct {
+	// The commit point of the transaction
+	Point uint64
+
+	// The old and new values of each subscribed Ref the transaction wrote, in order of Ref creation
+	Writes []RefWrite
+}
+
+// OverflowPolicy says what to do when a subscriber's buffer is full.
+type OverflowPolicy int
+
+const (
+	// Block the committing transaction until the subscriber receives.
+	Block OverflowPolicy = iota
+
+	// Discard the oldest undelivered event to make room.
+	DropOldest
+)
+
+// DefaultSubscribeBuffer is the buffer size used by Subscribe.
+const DefaultSubscribeBuffer = 64
+
+// SubscribeOptions configure a subscription.
+type SubscribeOptions struct {
+	// Number of events buffered for the subscriber (at least 1 with DropOldest)
+	Buffer int
+
+	Policy OverflowPolicy
+}
+
+// Commit events are published while the committing transaction holds the write locks on its Refs
+// and the feed lock, which is taken before the commit point.
+// So commits that publish are serialized, and every subscriber sees its events in commit-point order.
+// Transactions that write no subscribed Ref do not touch the feed lock:  they check a snapshot
+// of the subscriptions, so they are not held up by a commit waiting on a slow subscriber.
+
+type subscription struct {
+	ch     chan CommitEvent
+	refs   map[*Ref]bool
+	policy OverflowPolicy
+	done   chan struct{}
+}
+
+var feed struct {
+	// Held by a commit while it publishes, and while subscriptions change
+	lock sync.Mutex
+
+	// Subscriptions by Ref.  Replaced, not modified, under lock, so commits can check it without the lock.
+	byRef atomic.Pointer[map[*Ref][]*subscription]
+
+	// Separate, so that Unsubscribe can find a subscription while a commit holds the feed lock
+	chanLock sync.Mutex
+	byChan   map[<-chan CommitEvent]*subscription
+}
+
+// Subscribe returns a channel that receives an event for each commit that writes any of the given Refs.
+// The channel has a buffer of DefaultSubscribeBuffer events; when it is full, committing transactions block.
+//
+// Because a blocked commit holds the locks on the Refs it writes, a subscriber must keep receiving
+// while it runs transactions on subscribed Refs, or use DropOldest.
+func Subscribe(refs ...*Ref) <-chan CommitEvent {
+	return SubscribeWith(SubscribeOptions{Buffer: DefaultSubscribeBuffer}, refs...)
+}
+
+// SubscribeWith is Subscribe with control over the buffer size and overflow policy.
+func SubscribeWith(opts SubscribeOptions, refs ...*Ref) <-chan CommitEvent {
+	if opts.Buffer < 0 || (opts.Policy == DropOldest && opts.Buffer < 1) {
+		opts.Buffer = 1
+	}
+	sub := &subscription{
+		ch:     make(chan CommitEvent, opts.Buffer),
+		refs:   make(map[*Ref]bool, len(refs)),
+		policy: opts.Policy,
+		done:   make(chan struct{}),
+	}
+
+	feed.chanLock.Lock()
+	if feed.byChan == nil {
+		feed.byChan = make(map[<-chan CommitEvent]*subscription)
+	}
+	feed.byChan[sub.ch] = sub
+	feed.chanLock.Unlock()
+
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+	byRef := copySubscriptions()
+	for _, r := range refs {
+		if sub.refs[r] {
+			continue
+		}
+		sub.refs[r] = true
+		subs := byRef[r]
+		byRef[r] = append(subs[:len(subs):len(subs)], sub)
+	}
+	feed.byRef.Store(&byRef)
+	return sub.ch
+}
+
+// Unsubscribe stops delivery to a channel returned by Subscribe and closes it.
+// Events already buffered can still be received.
+func Unsubscribe(ch <-chan CommitEvent) {
+	feed.chanLock.Lock()
+	sub, ok := feed.byChan[ch]
+	if ok {
+		delete(feed.byChan, ch)
+	}
+	feed.chanLock.Unlock()
+	if !ok {
+		return
+	}
+
+	// Wake a commit blocked sending to ch, which holds the feed lock
+	close(sub.done)
+
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+	byRef := copySubscriptions()
+	for r := range sub.refs {
+		subs := byRef[r]
+		for i, s := range subs {
+			if s == sub {
+				subs = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(subs) == 0 {
+			delete(byRef, r)
+		} else {
+			byRef[r] = subs
+		}
+	}
+	feed.byRef.Store(&byRef)
+	close(sub.ch)
+}
+
+// A copy of the subscriptions by Ref, to modify and store.  Caller holds the feed lock.
+func copySubscriptions() map[*Ref][]*subscription {
+	byRef := make(map[*Ref][]*subscription)
+	if old := feed.byRef.Load(); old != nil {
+		for r, subs := range *old {
+			byRef[r] = subs
+		}
+	}
+	return byRef
+}
+
+// Returns true if any of refs is subscribed
+func subscribed(refs []*Ref) bool {
+	byRef := feed.byRef.Load()
+	if byRef == nil || len(*byRef) == 0 {
+		return false
+	}
+	for _, r := range refs {
+		if len((*byRef)[r]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Take the feed lock if any of refs is subscribed.  Returns true if the lock was taken.
+func lockFeedFor(refs []*Ref) bool {
+	if !subscribed(refs) {
+		return false
+	}
+	feed.lock.Lock()
+	// the subscriptions may have changed while we waited
+	if subscribed(refs) {
+		return true
+	}
+	feed.lock.Unlock()
+	return false
+}
+
+// Deliver the writes of a commit to the subscribers, then release the feed lock.
+func publish(point uint64, writes []RefWrite) {
+	defer feed.lock.Unlock()
+
+	byRef := *feed.byRef.Load()
+	var events map[*subscription]*CommitEvent
+	var order []*subscription
+	for _, w := range writes {
+		for _, sub := range byRef[w.Ref] {
+			ev, ok := events[sub]
+			if !ok {
+				if events == nil {
+					events = make(map[*subscription]*CommitEvent)
+				}
+				ev = &CommitEvent{Point: point}
+				events[sub] = ev
+				order = append(order, sub)
+			}
+			ev.Writes = append(ev.Writes, w)
+		}
+	}
+	for _, sub := range order {
+		sub.send(*events[sub])
+	}
+}
+
+func (sub *subscription) send(ev CommitEvent) {
+	if sub.policy == Block {
+		select {
+		case sub.ch <- ev:
+		case <-sub.done:
+		}
+		return
+	}
+	for {
+		select {
+		case sub.ch <- ev:
+			return
+		default:
+		}
+		select {
+		case <-sub.ch:
+		default:
+		}
+	}
+}
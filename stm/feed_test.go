@@ -0,0 +1,166 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func feedIncr(v interface{}, args ...interface{}) interface{} {
+	return v.(int) + 1
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	a, b, c := NewRef(0), NewRef(10), NewRef(100)
+	ch := Subscribe(a, b)
+	defer Unsubscribe(ch)
+
+	RunInTransaction(func(tx *Tx) interface{} {
+		a.Alter(tx, feedIncr)
+		b.Alter(tx, feedIncr)
+		c.Alter(tx, feedIncr)
+		return nil
+	})
+	RunInTransaction(func(tx *Tx) interface{} { return c.Alter(tx, feedIncr) })
+	RunInTransaction(func(tx *Tx) interface{} { return b.Set(tx, 20) })
+
+	ev := <-ch
+	if len(ev.Writes) != 2 {
+		t.Fatalf("first event has %d writes, want 2", len(ev.Writes))
+	}
+	if w := ev.Writes[0]; w.Ref != a || w.Old != 0 || w.New != 1 {
+		t.Errorf("first write = %v, want a 0 -> 1", w)
+	}
+	if w := ev.Writes[1]; w.Ref != b || w.Old != 10 || w.New != 11 {
+		t.Errorf("second write = %v, want b 10 -> 11", w)
+	}
+	ev2 := <-ch
+	if ev2.Point <= ev.Point {
+		t.Errorf("event points %d, %d not increasing", ev.Point, ev2.Point)
+	}
+	if len(ev2.Writes) != 1 || ev2.Writes[0].Ref != b || ev2.Writes[0].New != 20 {
+		t.Errorf("second event = %v, want b 11 -> 20", ev2)
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected event %v", ev)
+	default:
+	}
+}
+
+func TestSubscribeOrdered(t *testing.T) {
+	refs := []*Ref{NewRef(0), NewRef(0), NewRef(0), NewRef(0)}
+	ch := SubscribeWith(SubscribeOptions{Buffer: 1000}, refs...)
+	defer Unsubscribe(ch)
+
+	var wg sync.WaitGroup
+	for _, r := range refs {
+		wg.Add(1)
+		go func(r *Ref) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				RunInTransaction(func(tx *Tx) interface{} { return r.Alter(tx, feedIncr) })
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	var last uint64
+	for i := 0; i < 400; i++ {
+		ev := <-ch
+		if ev.Point <= last {
+			t.Fatalf("event %d: point %d after %d", i, ev.Point, last)
+		}
+		last = ev.Point
+	}
+}
+
+func TestSubscribeDropOldest(t *testing.T) {
+	r := NewRef(0)
+	ch := SubscribeWith(SubscribeOptions{Buffer: 2, Policy: DropOldest}, r)
+	defer Unsubscribe(ch)
+
+	for i := 0; i < 5; i++ {
+		RunInTransaction(func(tx *Tx) interface{} { return r.Alter(tx, feedIncr) })
+	}
+	if ev := <-ch; ev.Writes[0].New != 4 {
+		t.Errorf("oldest kept event has new value %v, want 4", ev.Writes[0].New)
+	}
+	if ev := <-ch; ev.Writes[0].New != 5 {
+		t.Errorf("newest event has new value %v, want 5", ev.Writes[0].New)
+	}
+}
+
+func TestSubscribeBlock(t *testing.T) {
+	r := NewRef(0)
+	ch := SubscribeWith(SubscribeOptions{Buffer: 1, Policy: Block}, r)
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 3; i++ {
+			RunInTransaction(func(tx *Tx) interface{} { return r.Alter(tx, feedIncr) })
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("commits did not block on a full subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if ev := <-ch; ev.Writes[0].New != 1 {
+		t.Errorf("first event has new value %v, want 1", ev.Writes[0].New)
+	}
+
+	// Unsubscribing releases the blocked commit
+	Unsubscribe(ch)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("commit still blocked after Unsubscribe")
+	}
+	for range ch {
+	}
+	if v := r.Deref(nil); v != 3 {
+		t.Errorf("final value %v, want 3", v)
+	}
+}
+
+func TestBlockedSubscriberDoesNotStallOtherRefs(t *testing.T) {
+	a, b := NewRef(0), NewRef(0)
+	ch := SubscribeWith(SubscribeOptions{Buffer: 0, Policy: Block}, a)
+
+	blocked := make(chan bool)
+	go func() {
+		RunInTransaction(func(tx *Tx) interface{} { return a.Alter(tx, feedIncr) })
+		close(blocked)
+	}()
+
+	select {
+	case <-blocked:
+		t.Fatalf("commit to a did not block on its subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunInTransaction(func(tx *Tx) interface{} { return b.Alter(tx, feedIncr) })
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("commit to b: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("commit to b stalled behind the blocked subscriber of a")
+	}
+
+	<-ch
+	<-blocked
+	Unsubscribe(ch)
+}
@@ -0,0 +1,111 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Point-in-time reads
+//
+// Each Ref keeps a limited history of committed values, tagged by commit point
+// (see SetMinHistory, SetMaxHistory).  The functions here read from that history
+// outside of a transaction.
+
+// ErrHistoryUnavailable indicates a Ref no longer has a value for a requested point.
+var ErrHistoryUnavailable = errors.New("Ref history not available for point")
+
+// A HistoryError identifies the Ref and point for which no value was retained.
+type HistoryError struct {
+	Ref   *Ref
+	Point uint64
+}
+
+func (e *HistoryError) Error() string {
+	return fmt.Sprintf("%v (ref %d, point %d)", ErrHistoryUnavailable, e.Ref.id, e.Point)
+}
+
+// Unwrap returns ErrHistoryUnavailable
+func (e *HistoryError) Unwrap() error {
+	return ErrHistoryUnavailable
+}
+
+// A HistoryEntry is a value of a Ref and the point at which it was committed.
+type HistoryEntry struct {
+	Point uint64
+	Val   interface{}
+}
+
+// CurrentPoint returns the most recent read/commit point.
+// Values committed at or before this point are visible to reads at this point.
+func CurrentPoint() uint64 {
+	return lastPoint.Current()
+}
+
+// ValueAt returns the value the Ref had at the given point.
+// Returns a *HistoryError if that value is no longer in the history.
+func (r *Ref) ValueAt(point uint64) (interface{}, error) {
+	r.enterReadLock()
+	defer r.exitReadLock()
+	if r.tvals == nil {
+		return nil, &HistoryError{Ref: r, Point: point}
+	}
+	ver := r.tvals
+	for {
+		if ver.point <= point {
+			return ver.val, nil
+		}
+		ver = ver.prior
+		if ver == r.tvals {
+			break
+		}
+	}
+	return nil, &HistoryError{Ref: r, Point: point}
+}
+
+// History returns the retained values of the Ref, oldest first.
+func (r *Ref) History() []HistoryEntry {
+	r.enterReadLock()
+	defer r.exitReadLock()
+	if r.tvals == nil {
+		return nil
+	}
+	h := make([]HistoryEntry, 0, r.calcHistoryCount()+1)
+	for tv := r.tvals.next; ; tv = tv.next {
+		h = append(h, HistoryEntry{Point: tv.point, Val: tv.val})
+		if tv == r.tvals {
+			break
+		}
+	}
+	return h
+}
+
+// A Snapshot reads Refs as of a single point, giving a consistent view of many Refs
+// without running a transaction.
+type Snapshot struct {
+	point uint64
+}
+
+// NewSnapshot returns a Snapshot at the current point.
+func NewSnapshot() Snapshot {
+	return Snapshot{point: CurrentPoint()}
+}
+
+// SnapshotAt returns a Snapshot at the given point.
+func SnapshotAt(point uint64) Snapshot {
+	return Snapshot{point: point}
+}
+
+// Point returns the point of the snapshot.
+func (s Snapshot) Point() uint64 {
+	return s.point
+}
+
+// Deref returns the value of the Ref at the snapshot's point.
+// Returns a *HistoryError if that value is no longer in the Ref's history.
+func (s Snapshot) Deref(r *Ref) (interface{}, error) {
+	return r.ValueAt(s.point)
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"testing"
+)
+
+func setInTx(r *Ref, v interface{}) {
+	RunInTransaction(func(tx *Tx) interface{} {
+		return r.Set(tx, v)
+	})
+}
+
+func TestValueAtAndHistory(t *testing.T) {
+	r := NewRef(0).SetMinHistory(5)
+
+	points := make([]uint64, 0, 3)
+	for i := 1; i <= 3; i++ {
+		setInTx(r, i)
+		points = append(points, CurrentPoint())
+	}
+
+	for i, p := range points {
+		v, err := r.ValueAt(p)
+		if err != nil || v != i+1 {
+			t.Errorf("ValueAt(%d) => (%v, %v), want (%d, nil)", p, v, err, i+1)
+		}
+	}
+
+	if v, err := r.ValueAt(points[0] - 1); err != nil || v != 0 {
+		t.Errorf("Expected the initial value before the first commit, got (%v, %v)", v, err)
+	}
+
+	h := r.History()
+	if len(h) != 4 {
+		t.Fatalf("Expected 4 history entries, got %v", h)
+	}
+	for i, e := range h {
+		if e.Val != i {
+			t.Errorf("History[%d] => %v, want %d", i, e.Val, i)
+		}
+		if i > 0 && e.Point != points[i-1] {
+			t.Errorf("History[%d] point => %d, want %d", i, e.Point, points[i-1])
+		}
+	}
+}
+
+func TestValueAtHistoryGone(t *testing.T) {
+	r := NewRef(0)
+	setInTx(r, 1)
+	p := CurrentPoint()
+	setInTx(r, 2)
+
+	// no faults and minHistory 0, so only the latest value is kept
+	_, err := r.ValueAt(p)
+	if !errors.Is(err, ErrHistoryUnavailable) {
+		t.Errorf("Expected ErrHistoryUnavailable, got %v", err)
+	}
+
+	var he *HistoryError
+	if !errors.As(err, &he) || he.Ref != r || he.Point != p {
+		t.Errorf("Expected a *HistoryError for r at %d, got %v", p, err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	r1 := NewRef("a").SetMinHistory(2)
+	r2 := NewRef("b").SetMinHistory(2)
+
+	snap := NewSnapshot()
+
+	RunInTransaction(func(tx *Tx) interface{} {
+		r1.Set(tx, "aa")
+		r2.Set(tx, "bb")
+		return nil
+	})
+
+	v1, e1 := snap.Deref(r1)
+	v2, e2 := snap.Deref(r2)
+	if e1 != nil || e2 != nil || v1 != "a" || v2 != "b" {
+		t.Errorf("Expected snapshot values a and b, got (%v, %v) and (%v, %v)", v1, e1, v2, e2)
+	}
+
+	now := SnapshotAt(CurrentPoint())
+	v1, _ = now.Deref(r1)
+	v2, _ = now.Deref(r2)
+	if v1 != "aa" || v2 != "bb" {
+		t.Errorf("Expected current values aa and bb, got %v and %v", v1, v2)
+	}
+
+	if now.Point() <= snap.Point() {
+		t.Errorf("Expected later snapshot to have a later point")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"time"
+)
+
+// Hooks let a test harness control time and interleaving in a transaction.
+// (See package stmtest.)  The zero value uses the real clock and real blocking.
+type Hooks struct {
+	// Now returns the current time.
+	// It is used for barge age, lock-wait timeouts, backoff, and statistics.
+	Now func() time.Time
+
+	// Yield is called at each point where another transaction may be interleaved:
+	// the start of each attempt, each Ref operation, the start of the commit,
+	// and repeatedly while waiting for a lock, for a conflicting transaction, or out a backoff.
+	// When Yield is set, those waits poll (calling Yield between tries) instead of blocking,
+	// so a harness that runs one transaction at a time can let the others make progress.
+	Yield func()
+}
+
+func (tx *Tx) now() time.Time {
+	if tx.opts.Hooks.Now != nil {
+		return tx.opts.Hooks.Now()
+	}
+	return time.Now()
+}
+
+func (tx *Tx) yield() {
+	if tx != nil && tx.opts.Hooks.Yield != nil {
+		tx.opts.Hooks.Yield()
+	}
+}
+
+func (tx *Tx) polling() bool {
+	return tx.opts.Hooks.Yield != nil
+}
+
+// Wait for the given duration
+func (tx *Tx) sleep(d time.Duration) {
+	if !tx.polling() {
+		time.Sleep(d)
+		return
+	}
+	deadline := tx.now().Add(d)
+	for tx.now().Before(deadline) {
+		tx.yield()
+	}
+}
+
+// Acquire a read lock on r
+func (tx *Tx) readLock(r *Ref) {
+	if !tx.polling() {
+		r.enterReadLock()
+		return
+	}
+	for !r.lock.TryRLock() {
+		tx.yield()
+	}
+}
+
+// Try to acquire a write lock on r, giving up after the given duration.
+// Returns true if the lock was acquired.
+func (tx *Tx) writeLock(r *Ref, d time.Duration) bool {
+	if !tx.polling() {
+		return r.tryEnterWriteLock(d)
+	}
+	deadline := tx.now().Add(d)
+	for !r.lock.TryLock() {
+		if !tx.now().Before(deadline) {
+			return false
+		}
+		tx.yield()
+	}
+	return true
+}
+
+// Wait for the transaction described by info to stop, giving up after the given duration.
+func (tx *Tx) await(info *TxInfo, d time.Duration) {
+	if !tx.polling() {
+		select {
+		case <-info.done:
+		case <-time.After(d):
+		}
+		return
+	}
+	deadline := tx.now().Add(d)
+	for tx.now().Before(deadline) {
+		select {
+		case <-info.done:
+			return
+		default:
+			tx.yield()
+		}
+	}
+}
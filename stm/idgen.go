@@ -17,3 +17,8 @@ type IDGenerator struct {
 func (g *IDGenerator) Next() uint64 {
 	return atomic.AddUint64(&g.id, 1)
 }
+
+// Current returns the most recent value returned by Next (0 if none)
+func (g *IDGenerator) Current() uint64 {
+	return atomic.LoadUint64(&g.id)
+}
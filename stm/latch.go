@@ -15,21 +15,27 @@ import (
 type CountDownLatch struct {
 	count       int32
 	zeroReached chan bool
-	timedOut    int32 // really a bool, but need atomic access
-	completed   int32 // really a bool, but need atomic access
+	zero        chan struct{} // closed when the count reaches zero
+	timedOut    int32         // really a bool, but need atomic access
+	completed   int32         // really a bool, but need atomic access
 	awaitMutex  *sync.Mutex
 	countMutex  *sync.Mutex
 }
 
 // NewCountDownLatch returns a CountDownLatch with an initial count
 func NewCountDownLatch(i int) *CountDownLatch {
-	return &CountDownLatch{count: int32(i),
+	c := &CountDownLatch{count: int32(i),
 		zeroReached: make(chan bool, 1),
+		zero:        make(chan struct{}),
 		timedOut:    0,
 		completed:   0,
 		awaitMutex:  new(sync.Mutex),
 		countMutex:  new(sync.Mutex),
 	}
+	if i <= 0 {
+		close(c.zero)
+	}
+	return c
 }
 
 // Completed returns true if the latch is completed (has already been awaited on)
@@ -58,9 +64,16 @@ func (c *CountDownLatch) CountDown() {
 	c.count--
 	if c.count <= 0 {
 		c.zeroReached <- true
+		close(c.zero)
 	}
 }
 
+// Done returns a channel that is closed when the count reaches zero.
+// Unlike Await, it can be used by any number of waiters, any number of times.
+func (c *CountDownLatch) Done() <-chan struct{} {
+	return c.zero
+}
+
 // Await waits up to the given amount of time for the latch to count down to zero.
 // Returns true if timed-out, false if zero reached
 func (c *CountDownLatch) Await(dur time.Duration) bool {
@@ -146,3 +146,30 @@ func TestCountDownTooFar(t *testing.T) {
 	c.CountDown()
 	n++
 }
+
+func TestLatchDone(t *testing.T) {
+	c := NewCountDownLatch(2)
+	select {
+	case <-c.Done():
+		t.Error("Done closed before count reached zero")
+	default:
+	}
+	c.CountDown()
+	c.CountDown()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-c.Done():
+		default:
+			t.Error("Done not closed after count reached zero")
+		}
+	}
+	if v := c.Await(time.Second); v {
+		t.Error("Await after Done should not time out")
+	}
+
+	select {
+	case <-NewCountDownLatch(0).Done():
+	default:
+		t.Error("Done not closed for a latch starting at zero")
+	}
+}
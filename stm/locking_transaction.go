@@ -5,13 +5,14 @@
 package stm
 
 import (
-	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// Defaults for TxOptions
 const (
 	// retryLimit is the number of times to retry a transaction in case of a conflict.
 	retryLimit = 10000
@@ -36,12 +37,20 @@ const (
 type TxInfo struct {
 	status     uint32
 	startPoint uint64
-	latch      *CountDownLatch
 	lock       sync.Mutex
+
+	// closed when the transaction stops (or is killed), releasing anyone blocked on it
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 func newTxInfo(status uint32, startPoint uint64) *TxInfo {
-	return &TxInfo{status: status, startPoint: startPoint, latch: NewCountDownLatch(1)}
+	return &TxInfo{status: status, startPoint: startPoint, done: make(chan struct{})}
+}
+
+// Release anyone blocked on this transaction.  Safe to call more than once.
+func (info *TxInfo) release() {
+	info.doneOnce.Do(func() { close(info.done) })
 }
 
 func (info *TxInfo) isRunning() bool {
@@ -70,8 +79,25 @@ type CFnCall struct {
 // A TxFn is a function suitable for calling in a transaction
 type TxFn func(*Tx) interface{}
 
+// A retrySignal is used in panics to signal a retry
+type retrySignal struct {
+	reason RetryReason
+	ref    *Ref
+	holder *TxInfo
+}
+
+func (s *retrySignal) Error() string {
+	return "Retry: " + s.reason.String()
+}
+
 // Cached error to use in panics to signal a retry
-var retryError error = errors.New("Retry")
+var retryError = &retrySignal{reason: RetryUnknown}
+
+// Abandon this attempt.
+// r is the Ref involved and holder the transaction holding it, if known.
+func (tx *Tx) retry(reason RetryReason, r *Ref, holder *TxInfo) {
+	panic(&retrySignal{reason: reason, ref: r, holder: holder})
+}
 
 // Tx provides STM transaction semantics for Agents and Refs
 type Tx struct {
@@ -101,10 +127,32 @@ type Tx struct {
 
 	// Refs holding read locks
 	ensures map[*Ref]bool
+
+	// Refs whose committed value was read in this attempt (allocated on first read)
+	reads map[*Ref]bool
+
+	// Retry and conflict policy
+	opts TxOptions
+
+	stats Stats
+
+	// Why attempts were retried, for the RetryLimitError
+	retryLog retryLog
 }
 
+// NewTx returns a transaction using DefaultTxOptions()
 func NewTx() *Tx {
+	return NewTxWithOptions(DefaultTxOptions())
+}
+
+// NewTxWithOptions returns a transaction using the given retry and conflict policy.
+// Zero fields in opts are filled in from DefaultTxOptions.
+func NewTxWithOptions(opts TxOptions) *Tx {
+	if opts.ReadOnly {
+		return &Tx{opts: opts.withDefaults()}
+	}
 	return &Tx{
+		opts:     opts.withDefaults(),
 		info:     nil,
 		vals:     make(map[*Ref]interface{}),
 		sets:     make(map[*Ref]bool),
@@ -131,8 +179,15 @@ func (tx *Tx) Stop(s uint32) {
 	if tx.info == nil {
 		return
 	}
+	if tx.opts.ReadOnly {
+		tx.info = nil
+		return
+	}
 
 	tx.info.setStatus(s, true)
+	for r := range tx.sets {
+		lockHolders.CompareAndDelete(r, tx.info)
+	}
 	tx.info = nil
 	tx.vals = make(map[*Ref]interface{})
 	tx.sets = make(map[*Ref]bool)
@@ -148,28 +203,40 @@ func (t *TxInfo) setStatus(s uint32, countDown bool) {
 	defer t.lock.Unlock()
 	atomic.StoreUint32(&t.status, s)
 	if countDown {
-		t.latch.CountDown()
+		t.release()
 	}
 }
 
-func tryWriteLock(r *Ref) {
-	if !r.tryEnterWriteLock(lockWaitMsecs) {
-		panic(retryError)
+func (tx *Tx) tryWriteLock(r *Ref) {
+	t0 := tx.now()
+	ok := tx.writeLock(r, tx.opts.LockWait)
+	tx.stats.LockWait += tx.now().Sub(t0)
+	if !ok {
+		atomic.AddUint64(&globalCounters.LockTimeouts, 1)
+		holder := r.tinfo.Load()
+		if holder != nil && !holder.isRunning() {
+			holder = nil
+		}
+		tx.retry(RetryLockTimeout, r, holder)
 	}
 }
 
 func (tx *Tx) releaseIfEnsured(r *Ref) {
 	if _, ok := tx.ensures[r]; ok {
 		delete(tx.ensures, r)
+		ensureHolders.Delete(holdKey{r, tx})
 		r.exitReadLock()
 	}
 }
 
-func (tx *Tx) blockAndBail(refinfo *TxInfo) interface{} {
+func (tx *Tx) blockAndBail(refinfo *TxInfo, reason RetryReason, r *Ref) interface{} {
 	// stop prior to blocking
 	tx.Stop(txRetry)
-	refinfo.latch.Await(lockWaitMsecs)
-	panic(retryError)
+	t0 := tx.now()
+	tx.await(refinfo, tx.opts.LockWait)
+	tx.stats.LockWait += tx.now().Sub(t0)
+	tx.retry(reason, r, refinfo)
+	return nil
 }
 
 func (tx *Tx) lockRef(r *Ref) interface{} {
@@ -184,25 +251,26 @@ func (tx *Tx) lockRef(r *Ref) interface{} {
 		}
 	}()
 
-	tryWriteLock(r)
+	tx.tryWriteLock(r)
 	locked = true
 
 	if r.currValPoint() > tx.readPoint {
-		panic(retryError)
+		tx.retry(RetryWriteConflict, r, nil)
 	}
 
-	refinfo := r.tinfo
+	refinfo := r.tinfo.Load()
 
 	// write lock conflict
 	if refinfo != nil && refinfo != tx.info && refinfo.isRunning() {
 		if !tx.barge(refinfo) {
 			r.exitWriteLock()
 			locked = false
-			return tx.blockAndBail(refinfo)
+			return tx.blockAndBail(refinfo, RetryBargeLost, r)
 		}
 	}
 
-	r.tinfo = tx.info
+	r.tinfo.Store(tx.info)
+	lockHolders.Store(r, tx.info)
 	return r.tryGetVal()
 }
 
@@ -211,7 +279,7 @@ func (tx *Tx) lockRef(r *Ref) interface{} {
 // Determine if sufficient clock time has elapsed to barge another transaction
 // Returns true if enough time elapsed, false otherwise
 func (tx *Tx) bargeTimeElapsed() bool {
-	return time.Now().Sub(tx.startTime) > bargeWaitNanos
+	return tx.now().Sub(tx.startTime) > tx.opts.BargeWait
 }
 
 // Try to barge a conflicting transation
@@ -222,7 +290,9 @@ func (tx *Tx) barge(refinfo *TxInfo) bool {
 	if tx.bargeTimeElapsed() && tx.startPoint < refinfo.startPoint {
 		barged = atomic.CompareAndSwapUint32(&refinfo.status, txRunning, txKilled)
 		if barged {
-			refinfo.latch.CountDown()
+			refinfo.release()
+			atomic.AddUint64(&globalCounters.Barges, 1)
+			tx.notify(func(o Observer) { o.OnBarge(tx, refinfo) })
 		}
 	}
 
@@ -236,131 +306,231 @@ func RunInTransaction(fn TxFn) (interface{}, error) {
 	return tx.Run(fn)
 }
 
+// Start a transaction with the given retry and conflict policy and invoke a function, passing it the transaction.
+// Returns the value computed by the function.
+func RunInTransactionWithOptions(opts TxOptions, fn TxFn) (interface{}, error) {
+	tx := NewTxWithOptions(opts)
+	return tx.Run(fn)
+}
+
 func (tx *Tx) Run(fn TxFn) (interface{}, error) {
+	if tx.opts.ReadOnly {
+		return tx.runReadOnly(fn)
+	}
 
-	done := false
-	locked := make([]*Ref, 0, 10)
-	// notify := make([]*Notify)
+	tx.stats = Stats{}
+	tx.retryLog = retryLog{}
+	t0 := tx.now()
+	atomic.AddUint64(&globalCounters.Started, 1)
+	tx.notify(func(o Observer) { o.OnStart(tx) })
 
 	defer func() {
-		for k := len(locked) - 1; k >= 0; k-- {
-			locked[k].exitWriteLock()
-		}
-
-		locked = nil
-		for r, _ := range tx.ensures {
-			r.exitReadLock()
-		}
-		tx.ensures = nil
-		if done {
-			tx.Stop(txCommitted)
-		} else {
-			tx.Stop(txRetry)
-		}
-		if done {
-			// do notifies and agent actions, if we every implement
-		}
-
+		tx.stats.Elapsed = tx.now().Sub(t0)
 	}()
 
-	for i := 0; !done && i < retryLimit; i++ {
-		ret, err := tx.tryRun(i, fn, &locked)
+	i := 0
+	for ; i < tx.opts.RetryLimit; i++ {
+		if i > 0 {
+			if d := tx.opts.Backoff.Delay(i); d > 0 {
+				tx.sleep(d)
+			}
+		}
+		ret, err := tx.tryRun(i, fn)
 		if err == nil {
-			done = true
 			return ret, nil
 		}
+		rs, ok := err.(*retrySignal)
+		if !ok {
+			return nil, tx.aborted(err)
+		}
+		tx.stats.Retries++
+		atomic.AddUint64(&globalCounters.Retries, 1)
+		tx.recordRetry(i, rs)
+		tx.notify(func(o Observer) { o.OnRetry(tx, rs.reason, rs.ref) })
 	}
 
-	return nil, errors.New("Transaction failed after reaching retry limit")
+	return nil, tx.aborted(tx.retryLimitError(i))
+}
+
+// Record that Run is returning an error without committing
+func (tx *Tx) aborted(err error) error {
+	atomic.AddUint64(&globalCounters.Aborted, 1)
+	tx.notify(func(o Observer) { o.OnAbort(tx, err) })
+	return err
+}
+
+// Stats returns statistics for the most recent Run of this transaction.
+func (tx *Tx) Stats() Stats {
+	return tx.stats
 }
 
 // One iteration of the Run loop
-// Split out so that we can catch a retry panic
-func (tx *Tx) tryRun(i int, fn TxFn, locked *[]*Ref) (ret interface{}, err error) {
+// Split out so that we can catch a retry panic.
+// Returns a *retrySignal if the iteration should be retried.
+// Returns other errors (aborts, validation failures, etc.) that end the transaction.
+// Locks are released and the TxInfo is stopped at the end of each iteration, committed or not.
+func (tx *Tx) tryRun(i int, fn TxFn) (ret interface{}, err error) {
 
 	ret, err = nil, nil
+	done := false
+	var locked []*Ref
 
 	defer func() {
+		for k := len(locked) - 1; k >= 0; k-- {
+			locked[k].exitWriteLock()
+		}
+		for r := range tx.ensures {
+			r.exitReadLock()
+			delete(tx.ensures, r)
+			ensureHolders.Delete(holdKey{r, tx})
+		}
+		if done {
+			tx.Stop(txCommitted)
+		} else {
+			tx.Stop(txRetry)
+		}
+
 		r := recover()
-		if r == retryError {
-			ret, err = nil, retryError
+		if rs, ok := r.(*retrySignal); ok {
+			ret, err = nil, rs
+		} else if e, ok := r.(error); ok && isTxError(e) {
+			ret, err = nil, e
 		} else if r != nil {
 			panic(r)
 		}
 	}()
 
+	tx.yield()
 	tx.getReadPoint()
+	tx.reads = nil
 	if i == 0 {
 		tx.startPoint = tx.readPoint
-		tx.startTime = time.Now()
+		tx.startTime = tx.now()
 	}
 
 	tx.info = newTxInfo(txRunning, tx.startPoint)
 	ret = fn(tx)
 
+	tx.yield()
+
 	// make sure no one has killed us before this point, and can't from now on
-	if atomic.CompareAndSwapUint32(&tx.info.status, txRunning, txCommitting) {
-		for r, calls := range tx.commutes {
-			if _, ok := tx.sets[r]; ok {
-				continue
-			}
-			_, wasEnsured := tx.ensures[r]
-			tx.releaseIfEnsured(r)
-			tryWriteLock(r)
-			*locked = append(*locked, r)
-			if wasEnsured && r.currValPoint() > tx.readPoint {
-				panic(retryError)
-			}
+	if !atomic.CompareAndSwapUint32(&tx.info.status, txRunning, txCommitting) {
+		tx.retry(RetryKilled, nil, nil)
+	}
 
-			refInfo := r.tinfo
-			if refInfo != nil && refInfo != tx.info && refInfo.isRunning() {
-				if !tx.barge(refInfo) {
-					panic(retryError)
-				}
-			}
-			val := r.tryGetVal()
-			tx.vals[r] = val
-			for _, call := range calls {
-				tx.vals[r] = call.fn(tx.vals[r], call.args...)
-			}
+	for _, r := range sortedRefs(tx.commutes) {
+		if _, ok := tx.sets[r]; ok {
+			continue
+		}
+		_, wasEnsured := tx.ensures[r]
+		tx.releaseIfEnsured(r)
+		tx.tryWriteLock(r)
+		locked = append(locked, r)
+		if wasEnsured && r.currValPoint() > tx.readPoint {
+			tx.retry(RetryWriteConflict, r, nil)
 		}
 
-		for r, _ := range tx.sets {
-			tryWriteLock(r)
-			*locked = append(*locked, r)
+		refInfo := r.tinfo.Load()
+		if refInfo != nil && refInfo != tx.info && refInfo.isRunning() {
+			if !tx.barge(refInfo) {
+				tx.retry(RetryBargeLost, r, refInfo)
+			}
+		}
+		val := r.tryGetVal()
+		tx.vals[r] = val
+		for _, call := range tx.commutes[r] {
+			tx.vals[r] = call.fn(tx.vals[r], call.args...)
 		}
+	}
+
+	for _, r := range sortedRefs(tx.sets) {
+		tx.tryWriteLock(r)
+		locked = append(locked, r)
+	}
 
-		// if we do validations for refs, it goes here
-
-		// at this point,
-		//    all values are calculated,
-		//    all refs to be written are locked
-		//    no more client code to be called
-		commitPoint := getCommitPoint()
-		for r, newV := range tx.vals {
-			//oldV := r.tryGetVal()
-			r.setValue(newV, commitPoint)
-			// todo: call notifies
+	refs := sortedRefs(tx.vals)
+
+	// at this point,
+	//    all values are calculated,
+	//    all refs to be written are locked
+	//    no more client code to be called
+	publishing := lockFeedFor(refs)
+	commitPoint := getCommitPoint()
+	if err := writeCommitLogs(commitPoint, refs, tx.vals); err != nil {
+		if publishing {
+			feed.lock.Unlock()
 		}
-		atomic.StoreUint32(&tx.info.status, txCommitted)
+		panic(err)
+	}
+	var writes []RefWrite
+	observed := tx.hasObservers()
+	if observed || publishing {
+		writes = make([]RefWrite, 0, len(refs))
+	}
+	for _, r := range refs {
+		newV := tx.vals[r]
+		if observed || publishing {
+			writes = append(writes, RefWrite{Ref: r, Old: r.tryGetVal(), New: newV})
+		}
+		r.setValue(newV, commitPoint)
+	}
+	if publishing {
+		publish(commitPoint, writes)
+	}
+	atomic.StoreUint32(&tx.info.status, txCommitted)
+	done = true
+	tx.stats.RefsWritten = len(refs)
+	tx.stats.RefsRead = len(tx.reads)
+	atomic.AddUint64(&globalCounters.Committed, 1)
+	if observed {
+		tx.notify(func(o Observer) { o.OnCommit(tx, commitPoint, writes) })
 	}
 
 	return
 }
 
-// Get the value of a Ref (most recently sent in this transaction or value prior to entering)
-func (tx *Tx) doGet(r *Ref) interface{} {
+// Refs in a map, in order of creation.
+// Locking and committing in a fixed order makes a transaction's behavior reproducible.
+func sortedRefs[V any](m map[*Ref]V) []*Ref {
+	refs := make([]*Ref, 0, len(m))
+	for r := range m {
+		refs = append(refs, r)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].id < refs[j].id })
+	return refs
+}
+
+// Make sure the transaction is live.
+// Panics with ErrNotInTransaction if there is no transaction, or signals a retry if we've been killed.
+func (tx *Tx) checkRunning() {
+	if tx == nil || tx.info == nil {
+		panic(ErrNotInTransaction)
+	}
 	if !tx.info.isRunning() {
-		panic(retryError)
+		tx.retry(RetryKilled, nil, nil)
 	}
+}
+
+// Get the value of a Ref (most recently sent in this transaction or value prior to entering)
+func (tx *Tx) doGet(r *Ref) interface{} {
+	tx.yield()
+	tx.checkRunning()
 	if v, ok := tx.vals[r]; ok {
 		return v
 	}
-	r.enterReadLock()
+	tx.readLock(r)
 	defer r.exitReadLock()
 	if r.tvals == nil {
 		panic(fmt.Errorf("%v is not bound", r))
 	}
+	if tx.opts.ReadOnly {
+		tx.stats.RefsRead++
+	} else {
+		if tx.reads == nil {
+			tx.reads = make(map[*Ref]bool)
+		}
+		tx.reads[r] = true
+	}
 	ver := r.tvals
 	for {
 		if ver.point <= tx.readPoint {
@@ -374,16 +544,18 @@ func (tx *Tx) doGet(r *Ref) interface{} {
 
 	// no version of val precedes the read point
 	r.addFault()
-	panic(retryError)
+	atomic.AddUint64(&globalCounters.Faults, 1)
+	tx.retry(RetryReadFault, r, nil)
+	return nil
 }
 
 // Set the value of a Ref inside the transaction
 func (tx *Tx) doSet(r *Ref, v interface{}) interface{} {
-	if !tx.info.isRunning() {
-		panic(retryError)
-	}
+	tx.yield()
+	tx.checkRunning()
+	tx.checkWritable(r)
 	if _, ok := tx.commutes[r]; ok {
-		panic(errors.New("Can't set after commute"))
+		panic(&SetAfterCommuteError{Ref: r})
 	}
 	if _, ok := tx.sets[r]; !ok {
 		tx.sets[r] = true
@@ -394,38 +566,43 @@ func (tx *Tx) doSet(r *Ref, v interface{}) interface{} {
 }
 
 func (tx *Tx) doEnsure(r *Ref) {
-	if !tx.info.isRunning() {
-		panic(retryError)
+	tx.yield()
+	tx.checkRunning()
+	if tx.opts.ReadOnly {
+		// reads are from a single snapshot already
+		return
 	}
 	if _, ok := tx.ensures[r]; ok {
 		return
 	}
-	r.enterReadLock()
+	tx.readLock(r)
 
 	// someone completed a write after our shapshot
 	if r.currValPoint() > tx.readPoint {
 		r.exitReadLock()
-		panic(retryError)
+		tx.retry(RetryEnsureConflict, r, nil)
 	}
 
-	refInfo := r.tinfo
+	refInfo := r.tinfo.Load()
 
 	// writer exists
 	if refInfo != nil && refInfo.isRunning() {
 		r.exitReadLock()
 		if refInfo != tx.info {
 			// not us, ensure is doomed
-			tx.blockAndBail(refInfo)
+			tx.blockAndBail(refInfo, RetryEnsureConflict, r)
 		}
 	} else {
 		tx.ensures[r] = true
+		ensureHolders.Store(holdKey{r, tx}, tx.info)
 	}
 }
 
 func (tx *Tx) GetAndStoreRefVal(r *Ref) {
+	tx.checkWritable(r)
 	if _, ok := tx.vals[r]; !ok {
 		var val interface{}
-		r.enterReadLock()
+		tx.readLock(r)
 		defer r.exitReadLock()
 		val = r.tryGetVal()
 		tx.vals[r] = val
@@ -434,9 +611,8 @@ func (tx *Tx) GetAndStoreRefVal(r *Ref) {
 
 // Post a commute on a ref into this transaction
 func (tx *Tx) doCommute(r *Ref, fn CFn, args ...interface{}) interface{} {
-	if !tx.info.isRunning() {
-		panic(retryError)
-	}
+	tx.yield()
+	tx.checkRunning()
 	tx.GetAndStoreRefVal(r)
 
 	calls, ok := tx.commutes[r]
@@ -453,10 +629,11 @@ func (tx *Tx) doCommute(r *Ref, fn CFn, args ...interface{}) interface{} {
 	return ret
 }
 
-// Kill this transaction
+// Kill this transaction.
+// Nothing is committed; Run returns ErrAborted.
 func (tx *Tx) Abort() {
 	tx.Stop(txKilled)
-	panic(errors.New("Transaction aborted")) // handle some other way?
+	panic(ErrAborted)
 }
 
 /*
@@ -0,0 +1,80 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"context"
+)
+
+// Nesting
+//
+// Clojure finds the enclosing transaction in a thread-local.
+// Go has no goroutine-local storage, so the enclosing transaction must be passed along,
+// either directly (RunIn) or in a context.Context (RunInContext).
+// A nested call joins the enclosing transaction:  the inner function runs as part of it,
+// a conflict retries the whole enclosing transaction, and nothing commits until the outermost Run does.
+
+// Running returns true if the transaction is currently executing its function.
+func (tx *Tx) Running() bool {
+	return tx != nil && tx.info != nil
+}
+
+// RunIn invokes fn as part of tx if tx is running.
+// Otherwise (including when tx is nil), it runs fn in a new transaction, like RunInTransaction.
+// When joining, errors (aborts, validation failures, etc.) are returned by the enclosing Run.
+func RunIn(tx *Tx, fn TxFn) (interface{}, error) {
+	if tx.Running() {
+		return fn(tx), nil
+	}
+	return RunInTransaction(fn)
+}
+
+// A CtxFn is a function suitable for calling in a transaction carried by a context
+type CtxFn func(ctx context.Context, tx *Tx) interface{}
+
+type txKey struct{}
+
+// NewContext returns a copy of ctx carrying tx.
+func NewContext(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// FromContext returns the transaction carried by ctx if it is running, else nil.
+func FromContext(ctx context.Context) *Tx {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok && tx.Running() {
+		return tx
+	}
+	return nil
+}
+
+// InTransaction returns true if ctx carries a running transaction.
+func InTransaction(ctx context.Context) bool {
+	return FromContext(ctx) != nil
+}
+
+// RunInContext invokes fn as part of the transaction carried by ctx, if there is one.
+// Otherwise it starts a new transaction and passes fn a context carrying it,
+// so that calls made by fn join it.
+func RunInContext(ctx context.Context, fn CtxFn) (interface{}, error) {
+	if tx := FromContext(ctx); tx != nil {
+		return fn(ctx, tx), nil
+	}
+	tx := NewTx()
+	return tx.Run(func(tx *Tx) interface{} {
+		return fn(NewContext(ctx, tx), tx)
+	})
+}
+
+// IO runs fn, which has side effects that cannot be undone, unless ctx carries a running transaction.
+// A transaction may be retried, so side effects inside one may happen more than once.
+// In that case fn is not called and ErrIOInTransaction is returned.
+// (This is Clojure's io! macro.)
+func IO(ctx context.Context, fn func()) error {
+	if InTransaction(ctx) {
+		return ErrIOInTransaction
+	}
+	fn()
+	return nil
+}
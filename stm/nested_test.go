@@ -0,0 +1,119 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunInJoinsEnclosingTx(t *testing.T) {
+	r1 := NewRef(1)
+	r2 := NewRef(2)
+
+	var inner *Tx
+
+	f := func(tx *Tx) interface{} {
+		r1.Set(tx, 10)
+		RunIn(tx, func(itx *Tx) interface{} {
+			inner = itx
+			r2.Set(itx, r1.Deref(itx).(int)+1)
+			return nil
+		})
+		tx.Abort()
+		return nil
+	}
+
+	tx := NewTx()
+	_, e := tx.Run(f)
+
+	if !errors.Is(e, ErrAborted) {
+		t.Errorf("Expected ErrAborted, got %v", e)
+	}
+
+	if inner != tx {
+		t.Errorf("Inner call should have joined the enclosing transaction")
+	}
+
+	if v1, v2 := r1.Deref(nil), r2.Deref(nil); v1 != 1 || v2 != 2 {
+		t.Errorf("Abort of the enclosing transaction should undo inner sets, got %v, %v", v1, v2)
+	}
+}
+
+func TestRunInWithoutTxStartsOne(t *testing.T) {
+	r1 := NewRef(1)
+
+	v, e := RunIn(nil, func(tx *Tx) interface{} {
+		return r1.Set(tx, 5)
+	})
+
+	if e != nil || v != 5 {
+		t.Errorf("Expected (5, nil), got (%v, %v)", v, e)
+	}
+
+	if v := r1.Deref(nil); v != 5 {
+		t.Errorf("Expected r1 to have value 5, got %v", v)
+	}
+}
+
+func TestRunInContextJoins(t *testing.T) {
+	r1 := NewRef(0)
+
+	incr := func(ctx context.Context) (interface{}, error) {
+		return RunInContext(ctx, func(ctx context.Context, tx *Tx) interface{} {
+			return r1.Set(tx, r1.Deref(tx).(int)+1)
+		})
+	}
+
+	var outer, inner *Tx
+	_, e := RunInContext(context.Background(), func(ctx context.Context, tx *Tx) interface{} {
+		outer = tx
+		incr(ctx)
+		inner = FromContext(ctx)
+		incr(ctx)
+		return nil
+	})
+
+	if e != nil {
+		t.Errorf("Expected no error, got %v", e)
+	}
+
+	if inner != outer {
+		t.Errorf("Context should carry the enclosing transaction")
+	}
+
+	if v := r1.Deref(nil); v != 2 {
+		t.Errorf("Expected r1 to have value 2, got %v", v)
+	}
+
+	if InTransaction(NewContext(context.Background(), outer)) {
+		t.Errorf("A finished transaction should not count as running")
+	}
+}
+
+func TestIOGuard(t *testing.T) {
+	n := 0
+	doIO := func() { n++ }
+
+	if err := IO(context.Background(), doIO); err != nil || n != 1 {
+		t.Errorf("IO outside a transaction should run, got %v and %d calls", err, n)
+	}
+
+	_, e := RunInContext(context.Background(), func(ctx context.Context, tx *Tx) interface{} {
+		if err := IO(ctx, doIO); err != nil {
+			panic(err)
+		}
+		return nil
+	})
+
+	if !errors.Is(e, ErrIOInTransaction) {
+		t.Errorf("Expected ErrIOInTransaction, got %v", e)
+	}
+
+	if n != 1 {
+		t.Errorf("IO inside a transaction should not run, got %d calls", n)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A RetryReason says why a transaction attempt was abandoned and retried.
+type RetryReason int
+
+const (
+	// RetryUnknown is an unclassified retry
+	RetryUnknown RetryReason = iota
+
+	// RetryReadFault: no value of a Ref old enough for the read point was left in its history
+	RetryReadFault
+
+	// RetryWriteConflict: a Ref was committed by another transaction after our read point
+	RetryWriteConflict
+
+	// RetryEnsureConflict: a Ref we tried to ensure is being written by another transaction
+	RetryEnsureConflict
+
+	// RetryBargeLost: another running transaction holds a Ref and could not be barged
+	RetryBargeLost
+
+	// RetryLockTimeout: we timed out waiting for a write lock on a Ref
+	RetryLockTimeout
+
+	// RetryKilled: we were barged by an older transaction
+	RetryKilled
+)
+
+var retryReasonNames = []string{
+	"unknown",
+	"read fault",
+	"write conflict",
+	"ensure conflict",
+	"barge lost",
+	"lock timeout",
+	"killed",
+}
+
+func (r RetryReason) String() string {
+	if r < 0 || int(r) >= len(retryReasonNames) {
+		return "invalid"
+	}
+	return retryReasonNames[r]
+}
+
+// A RefWrite records a value committed to a Ref
+type RefWrite struct {
+	Ref *Ref
+	Old interface{}
+	New interface{}
+}
+
+// An Observer receives notifications of transaction events.
+// Observers are called synchronously from the transaction's goroutine and should return quickly.
+// OnCommit is called while the written Refs are still locked, so it must not
+// run a transaction on them.
+type Observer interface {
+	// OnStart is called once when Tx.Run begins.
+	OnStart(tx *Tx)
+
+	// OnRetry is called when an attempt is abandoned.  ref is the Ref involved, if known.
+	OnRetry(tx *Tx, reason RetryReason, ref *Ref)
+
+	// OnBarge is called when tx kills the younger transaction victim.
+	OnBarge(tx *Tx, victim *TxInfo)
+
+	// OnCommit is called when tx commits at the given point.
+	OnCommit(tx *Tx, point uint64, writes []RefWrite)
+
+	// OnAbort is called when Tx.Run ends without committing.
+	OnAbort(tx *Tx, err error)
+}
+
+// NopObserver implements Observer with methods that do nothing.
+// Embed it to implement only the callbacks of interest.
+type NopObserver struct{}
+
+func (NopObserver) OnStart(tx *Tx)                                   {}
+func (NopObserver) OnRetry(tx *Tx, reason RetryReason, ref *Ref)     {}
+func (NopObserver) OnBarge(tx *Tx, victim *TxInfo)                   {}
+func (NopObserver) OnCommit(tx *Tx, point uint64, writes []RefWrite) {}
+func (NopObserver) OnAbort(tx *Tx, err error)                        {}
+
+// Global observers, copy-on-write.
+// Each registration gets its own entry, so an observer is removed by its registration
+// rather than by comparing observers, which need not be comparable.
+var (
+	observersLock sync.Mutex
+	observers     atomic.Value // []*observerEntry
+)
+
+type observerEntry struct {
+	o Observer
+}
+
+// AddObserver registers an Observer for all transactions.
+// Call the returned function to unregister it; calling it again does nothing.
+func AddObserver(o Observer) (remove func()) {
+	e := &observerEntry{o}
+	observersLock.Lock()
+	defer observersLock.Unlock()
+	old, _ := observers.Load().([]*observerEntry)
+	obs := make([]*observerEntry, len(old), len(old)+1)
+	copy(obs, old)
+	observers.Store(append(obs, e))
+	return func() { removeObserver(e) }
+}
+
+func removeObserver(e *observerEntry) {
+	observersLock.Lock()
+	defer observersLock.Unlock()
+	old, _ := observers.Load().([]*observerEntry)
+	obs := make([]*observerEntry, 0, len(old))
+	for _, x := range old {
+		if x != e {
+			obs = append(obs, x)
+		}
+	}
+	observers.Store(obs)
+}
+
+// Call fn on the transaction's own observer and all global observers
+func (tx *Tx) notify(fn func(o Observer)) {
+	if tx.opts.Observer != nil {
+		fn(tx.opts.Observer)
+	}
+	obs, _ := observers.Load().([]*observerEntry)
+	for _, e := range obs {
+		fn(e.o)
+	}
+}
+
+func (tx *Tx) hasObservers() bool {
+	obs, _ := observers.Load().([]*observerEntry)
+	return tx.opts.Observer != nil || len(obs) > 0
+}
+
+// Stats describes the work done by one transaction.
+// Read and write counts are for the final attempt.
+type Stats struct {
+	// Number of attempts abandoned and retried
+	Retries int
+
+	// Time from the start of Run to commit or abort
+	Elapsed time.Duration
+
+	// Time spent waiting for locks or blocked on conflicting transactions
+	LockWait time.Duration
+
+	// Number of distinct Refs whose committed value was read
+	RefsRead int
+
+	// Number of Refs written (set or commuted)
+	RefsWritten int
+}
+
+// Counters is a snapshot of the aggregate counts for all transactions.
+type Counters struct {
+	Started      uint64
+	Committed    uint64
+	Aborted      uint64
+	Retries      uint64
+	Barges       uint64
+	Faults       uint64
+	LockTimeouts uint64
+}
+
+var globalCounters Counters
+
+// ReadCounters returns a snapshot of the aggregate transaction counters.
+func ReadCounters() Counters {
+	return Counters{
+		Started:      atomic.LoadUint64(&globalCounters.Started),
+		Committed:    atomic.LoadUint64(&globalCounters.Committed),
+		Aborted:      atomic.LoadUint64(&globalCounters.Aborted),
+		Retries:      atomic.LoadUint64(&globalCounters.Retries),
+		Barges:       atomic.LoadUint64(&globalCounters.Barges),
+		Faults:       atomic.LoadUint64(&globalCounters.Faults),
+		LockTimeouts: atomic.LoadUint64(&globalCounters.LockTimeouts),
+	}
+}
+
+// Sub returns the change in counts from an earlier snapshot
+func (c Counters) Sub(earlier Counters) Counters {
+	return Counters{
+		Started:      c.Started - earlier.Started,
+		Committed:    c.Committed - earlier.Committed,
+		Aborted:      c.Aborted - earlier.Aborted,
+		Retries:      c.Retries - earlier.Retries,
+		Barges:       c.Barges - earlier.Barges,
+		Faults:       c.Faults - earlier.Faults,
+		LockTimeouts: c.LockTimeouts - earlier.LockTimeouts,
+	}
+}
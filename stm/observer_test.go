@@ -0,0 +1,175 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"testing"
+)
+
+type recordingObserver struct {
+	NopObserver
+	starts  int
+	retries []RetryReason
+	commits []uint64
+	writes  []RefWrite
+	aborts  []error
+}
+
+func (o *recordingObserver) OnStart(tx *Tx) {
+	o.starts++
+}
+
+func (o *recordingObserver) OnRetry(tx *Tx, reason RetryReason, ref *Ref) {
+	o.retries = append(o.retries, reason)
+}
+
+func (o *recordingObserver) OnCommit(tx *Tx, point uint64, writes []RefWrite) {
+	o.commits = append(o.commits, point)
+	o.writes = append(o.writes, writes...)
+}
+
+func (o *recordingObserver) OnAbort(tx *Tx, err error) {
+	o.aborts = append(o.aborts, err)
+}
+
+func TestObserverSeesCommit(t *testing.T) {
+	r1 := NewRef(1)
+	r2 := NewRef(2)
+	obs := new(recordingObserver)
+
+	n := 0
+	f := func(tx *Tx) interface{} {
+		n++
+		r1.Set(tx, r2.Deref(tx).(int)+10)
+		if n == 1 {
+			panic(retryError)
+		}
+		return nil
+	}
+
+	tx := NewTxWithOptions(TxOptions{Observer: obs})
+	if _, e := tx.Run(f); e != nil {
+		t.Fatalf("Expected no error, got %v", e)
+	}
+
+	if obs.starts != 1 {
+		t.Errorf("Expected 1 start, got %d", obs.starts)
+	}
+
+	if len(obs.retries) != 1 || obs.retries[0] != RetryUnknown {
+		t.Errorf("Expected one unknown retry, got %v", obs.retries)
+	}
+
+	if len(obs.commits) != 1 {
+		t.Fatalf("Expected 1 commit, got %d", len(obs.commits))
+	}
+
+	if p := r1.currValPoint(); p != obs.commits[0] {
+		t.Errorf("Expected commit point %d, got %d", p, obs.commits[0])
+	}
+
+	if len(obs.writes) != 1 || obs.writes[0].Ref != r1 || obs.writes[0].Old != 1 || obs.writes[0].New != 12 {
+		t.Errorf("Expected write of r1 from 1 to 12, got %v", obs.writes)
+	}
+
+	if len(obs.aborts) != 0 {
+		t.Errorf("Expected no aborts, got %v", obs.aborts)
+	}
+
+	stats := tx.Stats()
+	if stats.Retries != 1 || stats.RefsRead != 1 || stats.RefsWritten != 1 {
+		t.Errorf("Expected 1 retry, 1 ref read and 1 ref written, got %+v", stats)
+	}
+
+	if stats.Elapsed <= 0 {
+		t.Errorf("Expected positive elapsed time, got %v", stats.Elapsed)
+	}
+}
+
+func TestObserverSeesAbort(t *testing.T) {
+	obs := new(recordingObserver)
+	defer AddObserver(obs)()
+
+	before := ReadCounters()
+
+	RunInTransaction(func(tx *Tx) interface{} {
+		tx.Abort()
+		return nil
+	})
+
+	if len(obs.aborts) != 1 || !errors.Is(obs.aborts[0], ErrAborted) {
+		t.Errorf("Expected one abort with ErrAborted, got %v", obs.aborts)
+	}
+
+	delta := ReadCounters().Sub(before)
+	if delta.Started != 1 || delta.Aborted != 1 || delta.Committed != 0 {
+		t.Errorf("Expected counters to show one started and aborted transaction, got %+v", delta)
+	}
+}
+
+func TestRemoveObserver(t *testing.T) {
+	obs := new(recordingObserver)
+	remove := AddObserver(obs)
+	remove()
+	remove()
+
+	RunInTransaction(func(tx *Tx) interface{} { return nil })
+
+	if obs.starts != 0 {
+		t.Errorf("Removed observer should not be notified")
+	}
+}
+
+// An observer of an uncomparable type
+type sliceObserver struct {
+	NopObserver
+	starts *int
+	unused []int
+}
+
+func (o sliceObserver) OnStart(tx *Tx) {
+	*o.starts++
+}
+
+func TestRemoveUncomparableObserver(t *testing.T) {
+	var kept, removed int
+	defer AddObserver(sliceObserver{starts: &kept})()
+	remove := AddObserver(sliceObserver{starts: &removed})
+	remove()
+
+	RunInTransaction(func(tx *Tx) interface{} { return nil })
+
+	if kept != 1 || removed != 0 {
+		t.Errorf("Expected only the remaining observer to be notified, got %d and %d", kept, removed)
+	}
+}
+
+func TestCountersCountRetries(t *testing.T) {
+	before := ReadCounters()
+
+	n := 0
+	RunInTransaction(func(tx *Tx) interface{} {
+		n++
+		if n < 3 {
+			panic(retryError)
+		}
+		return nil
+	})
+
+	delta := ReadCounters().Sub(before)
+	if delta.Retries != 2 || delta.Committed != 1 {
+		t.Errorf("Expected 2 retries and 1 commit, got %+v", delta)
+	}
+}
+
+func TestRetryReasonString(t *testing.T) {
+	if s := RetryReadFault.String(); s != "read fault" {
+		t.Errorf("Expected 'read fault', got %q", s)
+	}
+	if s := RetryReason(-1).String(); s != "invalid" {
+		t.Errorf("Expected 'invalid', got %q", s)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"sync/atomic"
+)
+
+// A read-only transaction reads every Ref as of a single read point.
+// Because committing transactions hold write locks on their Refs from before they take
+// a commit point until their values are in place, reading at the current point
+// (without consuming a new one) still sees a consistent snapshot.
+// No write locks are taken, no bookkeeping maps are allocated, and there is no commit.
+// The only reason to retry is a read fault (history too short for the read point).
+
+// The TxInfo for read-only transactions.  It is never attached to a Ref, so it is never barged.
+var readOnlyTxInfo = &TxInfo{status: txRunning}
+
+// RunReadOnly invokes a function in a read-only transaction, passing it the transaction.
+// All Derefs see a consistent snapshot of the Refs.
+// Calling Set, Alter, or Commute returns a *ReadOnlyError from RunReadOnly.
+func RunReadOnly(fn TxFn) (interface{}, error) {
+	return RunInTransactionWithOptions(TxOptions{ReadOnly: true}, fn)
+}
+
+func (tx *Tx) runReadOnly(fn TxFn) (interface{}, error) {
+	tx.stats = Stats{}
+	tx.retryLog = retryLog{}
+	t0 := tx.now()
+	atomic.AddUint64(&globalCounters.Started, 1)
+	tx.notify(func(o Observer) { o.OnStart(tx) })
+
+	defer func() {
+		tx.stats.Elapsed = tx.now().Sub(t0)
+		tx.info = nil
+	}()
+
+	i := 0
+	for ; i < tx.opts.RetryLimit; i++ {
+		if i > 0 {
+			if d := tx.opts.Backoff.Delay(i); d > 0 {
+				tx.sleep(d)
+			}
+		}
+		ret, err := tx.tryRunReadOnly(i, fn)
+		if err == nil {
+			atomic.AddUint64(&globalCounters.Committed, 1)
+			if tx.hasObservers() {
+				tx.notify(func(o Observer) { o.OnCommit(tx, tx.readPoint, nil) })
+			}
+			return ret, nil
+		}
+		rs, ok := err.(*retrySignal)
+		if !ok {
+			return nil, tx.aborted(err)
+		}
+		tx.stats.Retries++
+		atomic.AddUint64(&globalCounters.Retries, 1)
+		tx.recordRetry(i, rs)
+		tx.notify(func(o Observer) { o.OnRetry(tx, rs.reason, rs.ref) })
+	}
+
+	return nil, tx.aborted(tx.retryLimitError(i))
+}
+
+// One iteration of the read-only Run loop
+func (tx *Tx) tryRunReadOnly(i int, fn TxFn) (ret interface{}, err error) {
+	defer func() {
+		r := recover()
+		if rs, ok := r.(*retrySignal); ok {
+			ret, err = nil, rs
+		} else if e, ok := r.(error); ok && isTxError(e) {
+			ret, err = nil, e
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	tx.yield()
+	tx.readPoint = lastPoint.Current()
+	if i == 0 {
+		tx.startPoint = tx.readPoint
+		tx.startTime = tx.now()
+	}
+	tx.stats.RefsRead = 0
+	tx.info = readOnlyTxInfo
+	return fn(tx), nil
+}
+
+// Make sure the transaction is allowed to change r
+func (tx *Tx) checkWritable(r *Ref) {
+	if tx.opts.ReadOnly {
+		panic(&ReadOnlyError{Ref: r})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestReadOnlyReadsValues(t *testing.T) {
+	r1 := NewRef(1)
+	r2 := NewRef(2)
+
+	RunInTransaction(func(tx *Tx) interface{} {
+		r1.Set(tx, 10)
+		return nil
+	})
+
+	pointBefore := lastPoint.Current()
+
+	v, e := RunReadOnly(func(tx *Tx) interface{} {
+		r1.Touch(tx)
+		return r1.Deref(tx).(int) + r2.Deref(tx).(int)
+	})
+
+	if e != nil {
+		t.Errorf("Expected no error, got %v", e)
+	}
+
+	if v != 12 {
+		t.Errorf("Expected 12, got %v", v)
+	}
+
+	if p := lastPoint.Current(); p != pointBefore {
+		t.Errorf("Read-only transaction should not consume a point, went from %d to %d", pointBefore, p)
+	}
+}
+
+func TestReadOnlyRejectsChanges(t *testing.T) {
+	r1 := NewRef(1)
+
+	fc := func(old interface{}, args ...interface{}) interface{} {
+		return old.(int) + 1
+	}
+
+	bodies := []TxFn{
+		func(tx *Tx) interface{} { return r1.Set(tx, 2) },
+		func(tx *Tx) interface{} { return r1.Alter(tx, fc) },
+		func(tx *Tx) interface{} { return r1.Commute(tx, fc) },
+	}
+
+	for i, f := range bodies {
+		_, e := RunReadOnly(f)
+		if !errors.Is(e, ErrReadOnly) {
+			t.Errorf("%d. Expected ErrReadOnly, got %v", i, e)
+		}
+		var roe *ReadOnlyError
+		if !errors.As(e, &roe) || roe.Ref != r1 {
+			t.Errorf("%d. Expected a *ReadOnlyError for r1, got %v", i, e)
+		}
+	}
+
+	if v := r1.Deref(nil); v != 1 {
+		t.Errorf("Expected r1 to be unchanged, got %v", v)
+	}
+}
+
+func TestReadOnlySeesConsistentSnapshot(t *testing.T) {
+	// Writers move amounts between two refs; the total must never change.
+	r1 := NewRef(100)
+	r2 := NewRef(0)
+	r1.SetMinHistory(5)
+	r2.SetMinHistory(5)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			RunInTransaction(func(tx *Tx) interface{} {
+				r1.Set(tx, r1.Deref(tx).(int)-1)
+				r2.Set(tx, r2.Deref(tx).(int)+1)
+				return nil
+			})
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		v, e := RunReadOnly(func(tx *Tx) interface{} {
+			return r1.Deref(tx).(int) + r2.Deref(tx).(int)
+		})
+		if e != nil {
+			t.Fatalf("Expected no error, got %v", e)
+		}
+		if v != 100 {
+			t.Fatalf("Expected a consistent total of 100, got %v", v)
+		}
+	}
+
+	wg.Wait()
+}
@@ -48,9 +48,21 @@ func (t *tval) setValue(val interface{}, point uint64) {
 	t.point = point
 }
 
+// Remove from the list.  Must not be the only tval.
+func (t *tval) unlink() {
+	t.prior.next = t.next
+	t.next.prior = t.prior
+	t.next = t
+	t.prior = t
+}
+
 const (
 	// DefaultMaxHistory is the default max history for Refs
 	DefaultMaxHistory = 10
+
+	// DefaultHistoryTrim is the default number of fault-free commits after which
+	// a Ref drops one history entry (down to its min history)
+	DefaultHistoryTrim = 100
 )
 
 // A Ref holds a value that can be updated in an STM transaction.
@@ -60,16 +72,32 @@ type Ref struct {
 	// Doubly-linked list, size controlled by history limit
 	tvals *tval
 
-	// Number of faults for the reference
+	// Number of faults for the reference since the history last grew
 	faults uint32
 
+	// Number of faults for the reference, ever
+	totalFaults uint64
+
+	// Number of commits since the last fault
+	quietCommits uint
+
+	// Number of quiet commits before trimming history (0 = never trim)
+	historyTrim uint
+
 	lock sync.RWMutex
 
 	minHistory uint
 	maxHistory uint
 
 	// TXInfo on the transaction locking this ref.
-	tinfo *TxInfo
+	// Atomic so that diagnostics can read it without the lock.
+	tinfo atomic.Pointer[TxInfo]
+
+	// Number of retries caused by conflicts on this Ref
+	retries uint64
+
+	// Written before each commit to this Ref, if not nil
+	commitLog *CommitLog
 
 	id uint64
 }
@@ -81,9 +109,10 @@ var refIds = new(IDGenerator)
 
 func NewRef(val interface{}) *Ref {
 	return &Ref{
-		id:         refIds.Next(),
-		maxHistory: DefaultMaxHistory,
-		tvals:      newTval(val, 0)}
+		id:          refIds.Next(),
+		maxHistory:  DefaultMaxHistory,
+		historyTrim: DefaultHistoryTrim,
+		tvals:       newTval(val, 0)}
 }
 
 // Getting values
@@ -125,12 +154,47 @@ func (r *Ref) MaxHistory() uint {
 	return r.maxHistory
 }
 
+// SetHistoryTrim sets the number of consecutive commits without read faults
+// after which the history shrinks by one entry (but not below min history).
+// Zero disables trimming.
+func (r *Ref) SetHistoryTrim(n uint) *Ref {
+	r.historyTrim = n
+	return r
+}
+
+func (r *Ref) HistoryTrim() uint {
+	return r.historyTrim
+}
+
+// HistoryCount returns the number of prior values retained (not counting the current value)
 func (r *Ref) HistoryCount() uint {
-	r.enterWriteLock()
-	defer r.exitWriteLock()
+	r.enterReadLock()
+	defer r.exitReadLock()
 	return r.calcHistoryCount()
 }
 
+// Faults returns the total number of read faults on this Ref.
+// A read fault occurs when a transaction needs a value older than any in the history.
+func (r *Ref) Faults() uint64 {
+	return atomic.LoadUint64(&r.totalFaults)
+}
+
+// ID returns a number identifying the Ref, unique within the process.
+// Refs are numbered in order of creation.
+func (r *Ref) ID() uint64 {
+	return r.id
+}
+
+// Retries returns the number of transaction retries caused by conflicts on this Ref.
+func (r *Ref) Retries() uint64 {
+	return atomic.LoadUint64(&r.retries)
+}
+
+// PendingFaults returns the number of read faults since the history last grew.
+func (r *Ref) PendingFaults() uint32 {
+	return r.getFault()
+}
+
 func (r *Ref) calcHistoryCount() uint {
 	if r.tvals == nil {
 		return 0
@@ -162,6 +226,9 @@ func (r *Ref) exitWriteLock() {
 }
 
 func (r *Ref) tryEnterWriteLock(dur time.Duration) bool {
+	if dur <= 0 {
+		return r.lock.TryLock()
+	}
 	lockChan := make(chan bool, 1)
 	toChan := time.After(dur)
 	timedOut := int32(0)
@@ -190,6 +257,7 @@ func (r *Ref) tryEnterWriteLock(dur time.Duration) bool {
 // Add to the fault count
 func (r *Ref) addFault() {
 	atomic.AddUint32(&r.faults, 1)
+	atomic.AddUint64(&r.totalFaults, 1)
 }
 
 func (r *Ref) getFault() uint32 {
@@ -218,18 +286,35 @@ func (r *Ref) tryGetVal() interface{} {
 	return r.tvals.val
 }
 
-// Set the value
+// Set the value.
+// The history grows by one entry (up to max history) if readers have faulted since the last commit.
+// It shrinks by one entry (down to min history) after historyTrim commits without a fault.
 func (r *Ref) setValue(val interface{}, commitPoint uint64) {
 	hcnt := r.calcHistoryCount()
+	faulted := r.getFault() > 0
+	if faulted {
+		r.quietCommits = 0
+	} else {
+		r.quietCommits++
+	}
 
 	if r.tvals == nil {
 		r.tvals = newTval(val, commitPoint)
-	} else if (r.getFault() > 0 && hcnt < r.maxHistory) || hcnt < r.minHistory {
+	} else if (faulted && hcnt < r.maxHistory) || hcnt < r.minHistory {
 		r.tvals = newTvalPrior(val, commitPoint, r.tvals)
 		r.setFault(0)
 	} else {
 		r.tvals = r.tvals.next
 		r.tvals.setValue(val, commitPoint)
+		if faulted {
+			// can't grow any more
+			r.setFault(0)
+		}
+		trim := r.historyTrim > 0 && r.quietCommits >= r.historyTrim
+		if hcnt > r.minHistory && (trim || hcnt > r.maxHistory) {
+			r.tvals.next.unlink()
+			r.quietCommits = 0
+		}
 	}
 }
 
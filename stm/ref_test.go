@@ -8,7 +8,8 @@ import (
 	"testing"
 )
 
-// Here we test only those parts of Ref that do not involve transactions
+// Here we test only those parts of Ref that do not involve transactions,
+// except for history management, which needs commits
 
 func TestNewRef(t *testing.T) {
 
@@ -24,8 +25,100 @@ func TestNewRef(t *testing.T) {
 		t.Errorf("For a new Ref, maxHistory should be %d, found %d", DefaultMaxHistory, h)
 	}
 
+	if h := r.HistoryTrim(); h != DefaultHistoryTrim {
+		t.Errorf("For a new Ref, historyTrim should be %d, found %d", DefaultHistoryTrim, h)
+	}
+
 	if v := r.Deref(nil); v != o {
 		t.Errorf("For a new Ref, value should be supplied object. Found %v, expected %v", v, o)
 	}
 
 }
+
+func commitN(r *Ref, n int) {
+	for i := 0; i < n; i++ {
+		RunInTransaction(func(tx *Tx) interface{} {
+			return r.Set(tx, i)
+		})
+	}
+}
+
+func TestHistoryGrowsOnFault(t *testing.T) {
+	r := NewRef(0)
+	commitN(r, 3)
+
+	if h := r.HistoryCount(); h != 0 {
+		t.Fatalf("Without faults, history should stay empty, found %d", h)
+	}
+
+	r.addFault()
+	r.addFault()
+
+	if f := r.Faults(); f != 2 {
+		t.Errorf("Expected 2 faults, found %d", f)
+	}
+
+	commitN(r, 1)
+
+	if h := r.HistoryCount(); h != 1 {
+		t.Errorf("After a fault, history should grow to 1, found %d", h)
+	}
+
+	if f := r.PendingFaults(); f != 0 {
+		t.Errorf("Growing the history should clear pending faults, found %d", f)
+	}
+
+	if f := r.Faults(); f != 2 {
+		t.Errorf("Total faults should not be cleared, found %d", f)
+	}
+}
+
+func TestHistoryGrowthCappedAtMax(t *testing.T) {
+	r := NewRef(0).SetMaxHistory(2)
+	for i := 0; i < 5; i++ {
+		r.addFault()
+		commitN(r, 1)
+	}
+
+	if h := r.HistoryCount(); h != 2 {
+		t.Errorf("History should be capped at max of 2, found %d", h)
+	}
+}
+
+func TestHistoryTrimsWhenQuiet(t *testing.T) {
+	r := NewRef(0).SetMinHistory(1).SetHistoryTrim(3)
+	for i := 0; i < 4; i++ {
+		r.addFault()
+		commitN(r, 1)
+	}
+
+	if h := r.HistoryCount(); h != 4 {
+		t.Fatalf("History should have grown to 4, found %d", h)
+	}
+
+	commitN(r, 3)
+	if h := r.HistoryCount(); h != 3 {
+		t.Errorf("After 3 quiet commits, history should shrink to 3, found %d", h)
+	}
+
+	commitN(r, 30)
+	if h := r.HistoryCount(); h != 1 {
+		t.Errorf("History should not shrink below min of 1, found %d", h)
+	}
+
+	hist := r.History()
+	if len(hist) != 2 || hist[1].Val != 29 || hist[0].Val != 28 {
+		t.Errorf("Expected the two most recent values to be kept, found %v", hist)
+	}
+}
+
+func TestHistoryTrimDisabled(t *testing.T) {
+	r := NewRef(0).SetHistoryTrim(0)
+	r.addFault()
+	commitN(r, 1)
+	commitN(r, 2*DefaultHistoryTrim)
+
+	if h := r.HistoryCount(); h != 1 {
+		t.Errorf("With trimming disabled, history should stay at 1, found %d", h)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stmtest runs STM transactions under a deterministic, seedable scheduler
+// and checks the outcome for serializability.
+//
+// Each transaction body runs on its own goroutine, but only one goroutine runs at a time.
+// At every scheduling point (see stm.Hooks) the running transaction hands control back
+// to the scheduler, which picks the next one to run using a random source seeded by Config.Seed.
+// Time is virtual: the clock advances a fixed tick per step, so lock-wait timeouts,
+// barging, and backoff all happen at reproducible points.
+// Given the same Config, a run makes the same choices, so a failure can be replayed from its seed.
+package stmtest
+
+import (
+	"fmt"
+	"github.com/dmiller/go-seq/sequtil"
+	"github.com/dmiller/go-seq/stm"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A Body is a transaction body run against the Refs of a test.
+// Bodies must be deterministic and have no effects other than through the Refs,
+// so that the test can replay them serially.
+type Body func(tx *stm.Tx, refs []*stm.Ref) interface{}
+
+// Config describes a test run.
+type Config struct {
+	// Initial values of the Refs
+	Init []interface{}
+
+	// Transaction bodies, each run once, concurrently
+	Bodies []Body
+
+	// Seed for the scheduler
+	Seed int64
+
+	// Maximum number of scheduling steps before giving up (default 1000000)
+	MaxSteps int
+
+	// Virtual time that passes at each step (default 1ms)
+	Tick time.Duration
+
+	// Base options for the transactions.  Hooks and Observer are supplied by the scheduler.
+	Options stm.TxOptions
+}
+
+// Result describes the outcome of a run.
+type Result struct {
+	Seed int64
+
+	// The transaction chosen at each scheduling step
+	Schedule []int
+
+	// Return value and error from each body's transaction
+	Returns []interface{}
+	Errors  []error
+
+	// The transactions that committed, in commit order
+	CommitOrder []int
+
+	// Final values of the Refs
+	Final []interface{}
+}
+
+const (
+	defaultMaxSteps = 1000000
+	defaultTick     = time.Millisecond
+)
+
+// A worker reports to the scheduler when it yields or finishes
+type event struct {
+	id   int
+	done bool
+}
+
+type scheduler struct {
+	rng     *rand.Rand
+	clock   time.Time
+	tick    time.Duration
+	resume  []chan struct{}
+	parked  chan event
+	stop    chan struct{} // closed when Run gives up
+	current int
+}
+
+// Panic value that unwinds a worker when Run gives up
+type stopped struct{}
+
+func (s *scheduler) now() time.Time {
+	return s.clock
+}
+
+// Called on the worker's goroutine: hand control back and wait to be resumed
+func (s *scheduler) yield(id int) {
+	s.parked <- event{id: id}
+	s.wait(id)
+}
+
+// Wait to be resumed.  If Run gives up instead, unwind the worker.
+func (s *scheduler) wait(id int) {
+	select {
+	case <-s.resume[id]:
+	case <-s.stop:
+		panic(stopped{})
+	}
+}
+
+type commitRecorder struct {
+	stm.NopObserver
+	s     *scheduler
+	order *[]commit
+}
+
+type commit struct {
+	id    int
+	point uint64
+}
+
+// Only one worker runs at a time, so the current worker is the one committing
+func (c *commitRecorder) OnCommit(tx *stm.Tx, point uint64, writes []stm.RefWrite) {
+	*c.order = append(*c.order, commit{id: c.s.current, point: point})
+}
+
+// Run runs the bodies of the configuration concurrently under the deterministic scheduler.
+// Returns an error if the run does not finish within the step limit or a body panics.
+// Transactions still running at the step limit are unwound before Run returns.
+func Run(cfg Config) (*Result, error) {
+	maxSteps := cfg.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+	tick := cfg.Tick
+	if tick <= 0 {
+		tick = defaultTick
+	}
+
+	n := len(cfg.Bodies)
+	s := &scheduler{
+		rng:    rand.New(rand.NewSource(cfg.Seed)),
+		clock:  time.Unix(0, 0),
+		tick:   tick,
+		resume: make([]chan struct{}, n),
+		parked: make(chan event),
+		stop:   make(chan struct{}),
+	}
+
+	refs := newRefs(cfg.Init)
+	res := &Result{
+		Seed:    cfg.Seed,
+		Returns: make([]interface{}, n),
+		Errors:  make([]error, n),
+	}
+	var commits []commit
+	panics := make([]interface{}, n)
+	var workers sync.WaitGroup
+
+	for i := range cfg.Bodies {
+		s.resume[i] = make(chan struct{})
+		workers.Add(1)
+		go func(id int) {
+			defer workers.Done()
+			defer func() {
+				p := recover()
+				if _, ok := p.(stopped); ok {
+					return
+				}
+				panics[id] = p
+				s.parked <- event{id: id, done: true}
+			}()
+			s.wait(id)
+			opts := cfg.Options
+			opts.Hooks = stm.Hooks{Now: s.now, Yield: func() { s.yield(id) }}
+			opts.Observer = &commitRecorder{s: s, order: &commits}
+			body := cfg.Bodies[id]
+			res.Returns[id], res.Errors[id] = stm.RunInTransactionWithOptions(opts, func(tx *stm.Tx) interface{} {
+				return body(tx, refs)
+			})
+		}(i)
+	}
+
+	live := make([]int, n)
+	for i := range live {
+		live[i] = i
+	}
+
+	for steps := 0; len(live) > 0; steps++ {
+		if steps >= maxSteps {
+			// unwind the parked workers, releasing any locks they hold
+			close(s.stop)
+			workers.Wait()
+			return res, fmt.Errorf("stmtest: seed %d: no result after %d steps", cfg.Seed, maxSteps)
+		}
+		k := s.rng.Intn(len(live))
+		s.current = live[k]
+		res.Schedule = append(res.Schedule, s.current)
+		s.clock = s.clock.Add(s.tick)
+		s.resume[s.current] <- struct{}{}
+		ev := <-s.parked
+		if ev.done {
+			live = append(live[:k], live[k+1:]...)
+		}
+	}
+
+	for i, p := range panics {
+		if p != nil {
+			return res, fmt.Errorf("stmtest: seed %d: body %d panicked: %v", cfg.Seed, i, p)
+		}
+	}
+
+	for _, c := range sortCommits(commits) {
+		res.CommitOrder = append(res.CommitOrder, c.id)
+	}
+	res.Final = derefAll(refs)
+	return res, nil
+}
+
+// CheckSerializable replays the committed bodies serially, in commit order, against fresh Refs.
+// Returns an error if any body's result or the final Ref values differ from the concurrent run.
+func (res *Result) CheckSerializable(cfg Config) error {
+	refs := newRefs(cfg.Init)
+	for _, id := range res.CommitOrder {
+		body := cfg.Bodies[id]
+		v, err := stm.RunInTransaction(func(tx *stm.Tx) interface{} {
+			return body(tx, refs)
+		})
+		if err != nil {
+			return fmt.Errorf("stmtest: seed %d: serial replay of body %d failed: %v", res.Seed, id, err)
+		}
+		if !sequtil.Equiv(v, res.Returns[id]) {
+			return fmt.Errorf("stmtest: seed %d: body %d returned %v concurrently, %v serially (commit order %v)",
+				res.Seed, id, res.Returns[id], v, res.CommitOrder)
+		}
+	}
+	final := derefAll(refs)
+	for i := range final {
+		if !sequtil.Equiv(final[i], res.Final[i]) {
+			return fmt.Errorf("stmtest: seed %d: ref %d is %v concurrently, %v serially (commit order %v)",
+				res.Seed, i, res.Final[i], final[i], res.CommitOrder)
+		}
+	}
+	return nil
+}
+
+// Check runs the configuration and checks the result for serializability.
+// Every body is expected to commit.
+func Check(cfg Config) (*Result, error) {
+	res, err := Run(cfg)
+	if err != nil {
+		return res, err
+	}
+	for i, e := range res.Errors {
+		if e != nil {
+			return res, fmt.Errorf("stmtest: seed %d: body %d failed: %v", cfg.Seed, i, e)
+		}
+	}
+	return res, res.CheckSerializable(cfg)
+}
+
+// Explore runs Check with seeds cfg.Seed, cfg.Seed+1, ..., cfg.Seed+n-1.
+// Returns the first failure; its message includes the seed to replay.
+func Explore(cfg Config, n int) error {
+	for i := 0; i < n; i++ {
+		c := cfg
+		c.Seed = cfg.Seed + int64(i)
+		if _, err := Check(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newRefs(init []interface{}) []*stm.Ref {
+	refs := make([]*stm.Ref, len(init))
+	for i, v := range init {
+		refs[i] = stm.NewRef(v)
+	}
+	return refs
+}
+
+func derefAll(refs []*stm.Ref) []interface{} {
+	vals := make([]interface{}, len(refs))
+	for i, r := range refs {
+		vals[i] = r.Deref(nil)
+	}
+	return vals
+}
+
+// Commits are recorded in the order they happen, which under the scheduler is commit-point order,
+// but sort anyway rather than depend on that.
+func sortCommits(cs []commit) []commit {
+	for i := 1; i < len(cs); i++ {
+		for j := i; j > 0 && cs[j].point < cs[j-1].point; j-- {
+			cs[j], cs[j-1] = cs[j-1], cs[j]
+		}
+	}
+	return cs
+}
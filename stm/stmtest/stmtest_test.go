@@ -0,0 +1,162 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stmtest
+
+import (
+	"github.com/dmiller/go-seq/stm"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func incr(v interface{}, args ...interface{}) interface{} {
+	return v.(int) + 1
+}
+
+func transfer(from, to, amt int) Body {
+	return func(tx *stm.Tx, refs []*stm.Ref) interface{} {
+		f := refs[from].Deref(tx).(int)
+		t := refs[to].Deref(tx).(int)
+		refs[from].Set(tx, f-amt)
+		refs[to].Set(tx, t+amt)
+		return f
+	}
+}
+
+func total(tx *stm.Tx, refs []*stm.Ref) interface{} {
+	sum := 0
+	for _, r := range refs {
+		sum += r.Deref(tx).(int)
+	}
+	return sum
+}
+
+func TestBankTransfers(t *testing.T) {
+	cfg := Config{
+		Init: []interface{}{100, 100, 100},
+		Bodies: []Body{
+			transfer(0, 1, 10),
+			transfer(1, 2, 20),
+			transfer(2, 0, 30),
+			total,
+			transfer(0, 2, 5),
+		},
+	}
+	if err := Explore(cfg, 50); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCommuteCounters(t *testing.T) {
+	body := func(tx *stm.Tx, refs []*stm.Ref) interface{} {
+		refs[0].Commute(tx, incr)
+		refs[1].Alter(tx, incr)
+		return nil
+	}
+	cfg := Config{
+		Init:   []interface{}{0, 0},
+		Bodies: []Body{body, body, body, body},
+	}
+	for seed := int64(0); seed < 50; seed++ {
+		cfg.Seed = seed
+		res, err := Check(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Final[0] != 4 || res.Final[1] != 4 {
+			t.Errorf("seed %d: final values %v, want [4 4]", seed, res.Final)
+		}
+	}
+}
+
+func TestEnsureVsCommute(t *testing.T) {
+	// Write skew is prevented by ensuring the Ref read but not written
+	guard := func(mine, other int) Body {
+		return func(tx *stm.Tx, refs []*stm.Ref) interface{} {
+			refs[other].Touch(tx)
+			if refs[mine].Deref(tx).(int)+refs[other].Deref(tx).(int) < 2 {
+				return false
+			}
+			refs[mine].Alter(tx, func(v interface{}, args ...interface{}) interface{} { return v.(int) - 1 })
+			return true
+		}
+	}
+	bump := func(tx *stm.Tx, refs []*stm.Ref) interface{} {
+		refs[0].Commute(tx, incr)
+		return nil
+	}
+	cfg := Config{
+		Init:   []interface{}{1, 1},
+		Bodies: []Body{guard(0, 1), guard(1, 0), bump},
+	}
+	if err := Explore(cfg, 50); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSameSeedSameSchedule(t *testing.T) {
+	cfg := Config{
+		Init:   []interface{}{100, 100},
+		Bodies: []Body{transfer(0, 1, 1), transfer(1, 0, 2), transfer(0, 1, 3)},
+		Seed:   42,
+	}
+	r1, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r1.Schedule, r2.Schedule) {
+		t.Errorf("schedules differ for the same seed:\n%v\n%v", r1.Schedule, r2.Schedule)
+	}
+	if !reflect.DeepEqual(r1.CommitOrder, r2.CommitOrder) {
+		t.Errorf("commit orders differ for the same seed: %v, %v", r1.CommitOrder, r2.CommitOrder)
+	}
+}
+
+func TestCheckDetectsMismatch(t *testing.T) {
+	cfg := Config{
+		Init:   []interface{}{0},
+		Bodies: []Body{func(tx *stm.Tx, refs []*stm.Ref) interface{} { return refs[0].Alter(tx, incr) }},
+	}
+	res, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Final[0] = 7
+	if res.CheckSerializable(cfg) == nil {
+		t.Errorf("CheckSerializable accepted a wrong final value")
+	}
+}
+
+func TestStepLimitStopsWorkers(t *testing.T) {
+	spin := func(tx *stm.Tx, refs []*stm.Ref) interface{} {
+		for i := 0; i < 1000; i++ {
+			refs[0].Alter(tx, incr)
+		}
+		return nil
+	}
+	cfg := Config{
+		Init:     []interface{}{0},
+		Bodies:   []Body{spin, spin, spin},
+		MaxSteps: 10,
+	}
+
+	before := runtime.NumGoroutine()
+	if _, err := Run(cfg); err == nil {
+		t.Fatalf("Run finished within %d steps", cfg.MaxSteps)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left running after Run gave up", n-before)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// TxOptions controls the retry and conflict policy of a transaction.
+// A zero field means use the corresponding value from DefaultTxOptions.
+// To ask for a zero LockWait or BargeWait, use NoWait.
+type TxOptions struct {
+	// RetryLimit is the maximum number of attempts before Run gives up.
+	RetryLimit int
+
+	// LockWait is how long to wait to acquire a write lock on a Ref
+	// (and how long to block on a conflicting transaction) before retrying.
+	// NoWait means retry at once if the lock is held.
+	LockWait time.Duration
+
+	// BargeWait is how long a transaction must have been running before it may barge
+	// (kill) a younger conflicting transaction.
+	// NoWait means it may barge as soon as it starts.
+	BargeWait time.Duration
+
+	// Backoff determines the pause between one attempt and the next.
+	Backoff Backoff
+
+	// ReadOnly requests the read-only fast path.
+	// See RunReadOnly.
+	ReadOnly bool
+
+	// Hooks replace the clock and blocking waits, for testing.
+	Hooks Hooks
+
+	// Observer, if not nil, is notified of events in this transaction
+	// (in addition to any observers registered with AddObserver).
+	Observer Observer
+}
+
+// NoWait, as a LockWait or BargeWait, asks for a wait of zero
+// (a zero field means use the default).
+const NoWait time.Duration = -1
+
+// DefaultTxOptions returns the policy used by NewTx and RunInTransaction.
+func DefaultTxOptions() TxOptions {
+	return TxOptions{
+		RetryLimit: retryLimit,
+		LockWait:   lockWaitMsecs,
+		BargeWait:  bargeWaitNanos,
+		Backoff:    NoBackoff,
+	}
+}
+
+// withDefaults returns a copy of the options with zero fields filled in from DefaultTxOptions
+// and NoWait (or any negative wait) replaced by zero
+func (o TxOptions) withDefaults() TxOptions {
+	d := DefaultTxOptions()
+	if o.RetryLimit <= 0 {
+		o.RetryLimit = d.RetryLimit
+	}
+	o.LockWait = waitOrDefault(o.LockWait, d.LockWait)
+	o.BargeWait = waitOrDefault(o.BargeWait, d.BargeWait)
+	if o.Backoff == nil {
+		o.Backoff = d.Backoff
+	}
+	return o
+}
+
+func waitOrDefault(w time.Duration, def time.Duration) time.Duration {
+	switch {
+	case w == 0:
+		return def
+	case w < 0:
+		return 0
+	}
+	return w
+}
+
+// A Backoff computes the pause before a transaction is retried.
+type Backoff interface {
+	// Delay returns how long to wait before retry number n (the first retry is 1).
+	Delay(n int) time.Duration
+}
+
+type noBackoff struct{}
+
+func (noBackoff) Delay(n int) time.Duration {
+	return 0
+}
+
+// NoBackoff retries immediately.  This is the default.
+var NoBackoff Backoff = noBackoff{}
+
+// ExponentialBackoff doubles the delay on each retry, starting at Base and capped at Max.
+// A zero Max means no cap.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns Base * 2^(n-1), capped at Max
+func (b ExponentialBackoff) Delay(n int) time.Duration {
+	return expDelay(b.Base, b.Max, n)
+}
+
+// JitteredBackoff picks a random delay between zero and the ExponentialBackoff delay for the same retry.
+// Spreading the retries of conflicting transactions makes it less likely they collide again.
+type JitteredBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns a random duration in [0, Base * 2^(n-1)], capped at Max
+func (b JitteredBackoff) Delay(n int) time.Duration {
+	d := expDelay(b.Base, b.Max, n)
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func expDelay(base time.Duration, max time.Duration, n int) time.Duration {
+	if base <= 0 || n <= 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < n && (max <= 0 || d < max); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d
+}
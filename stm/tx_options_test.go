@@ -0,0 +1,177 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultTxOptions(t *testing.T) {
+	tx := NewTx()
+
+	if tx.opts.RetryLimit != retryLimit {
+		t.Errorf("Default retry limit should be %d, found %d", retryLimit, tx.opts.RetryLimit)
+	}
+
+	if tx.opts.LockWait != lockWaitMsecs {
+		t.Errorf("Default lock wait should be %v, found %v", lockWaitMsecs, tx.opts.LockWait)
+	}
+
+	if tx.opts.BargeWait != bargeWaitNanos {
+		t.Errorf("Default barge wait should be %v, found %v", bargeWaitNanos, tx.opts.BargeWait)
+	}
+
+	if tx.opts.Backoff != NoBackoff {
+		t.Errorf("Default backoff should be NoBackoff, found %v", tx.opts.Backoff)
+	}
+}
+
+func TestTxOptionsZeroFieldsDefaulted(t *testing.T) {
+	tx := NewTxWithOptions(TxOptions{RetryLimit: 5})
+
+	if tx.opts.RetryLimit != 5 {
+		t.Errorf("Retry limit should be 5, found %d", tx.opts.RetryLimit)
+	}
+
+	if tx.opts.LockWait != lockWaitMsecs {
+		t.Errorf("Unset lock wait should default to %v, found %v", lockWaitMsecs, tx.opts.LockWait)
+	}
+
+	if tx.opts.Backoff == nil {
+		t.Errorf("Unset backoff should default to NoBackoff")
+	}
+}
+
+func TestDefaultTxOptionsIsACopy(t *testing.T) {
+	d := DefaultTxOptions()
+	d.RetryLimit = 1
+
+	if n := DefaultTxOptions().RetryLimit; n != retryLimit {
+		t.Errorf("Changing a copy of the defaults should not change them, found retry limit %d", n)
+	}
+
+	if n := NewTx().opts.RetryLimit; n != retryLimit {
+		t.Errorf("Changing a copy of the defaults should not change NewTx, found retry limit %d", n)
+	}
+}
+
+func TestNoWait(t *testing.T) {
+	tx := NewTxWithOptions(TxOptions{LockWait: NoWait, BargeWait: NoWait})
+
+	if tx.opts.LockWait != 0 {
+		t.Errorf("NoWait lock wait should be 0, found %v", tx.opts.LockWait)
+	}
+
+	if tx.opts.BargeWait != 0 {
+		t.Errorf("NoWait barge wait should be 0, found %v", tx.opts.BargeWait)
+	}
+
+	r := NewRef(0)
+	r.enterReadLock()
+	defer r.exitReadLock()
+
+	t0 := time.Now()
+	_, e := RunInTransactionWithOptions(TxOptions{LockWait: NoWait, RetryLimit: 3}, func(tx *Tx) interface{} {
+		r.Set(tx, 1)
+		return nil
+	})
+	dur := time.Now().Sub(t0)
+
+	if e == nil {
+		t.Errorf("Expected an error setting a locked ref")
+	}
+
+	// the default lock wait is 100 msecs per attempt
+	if dur > 50*time.Millisecond {
+		t.Errorf("Expected no wait for the lock, took %v", dur)
+	}
+}
+
+func TestRetryLimitOption(t *testing.T) {
+	n := 0
+	f := func(tx *Tx) interface{} {
+		n++
+		panic(retryError)
+	}
+
+	v, e := RunInTransactionWithOptions(TxOptions{RetryLimit: 3}, f)
+
+	if e == nil {
+		t.Errorf("Expected an error after reaching the retry limit, got value %v", v)
+	}
+
+	if n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+}
+
+func TestBackoffIsApplied(t *testing.T) {
+	n := 0
+	f := func(tx *Tx) interface{} {
+		n++
+		if n < 4 {
+			panic(retryError)
+		}
+		return n
+	}
+
+	opts := TxOptions{Backoff: ExponentialBackoff{Base: 10 * time.Millisecond}}
+
+	t0 := time.Now()
+	v, e := RunInTransactionWithOptions(opts, f)
+	dur := time.Now().Sub(t0)
+
+	if e != nil {
+		t.Errorf("Expected no error, got %v", e)
+	}
+
+	if v != 4 {
+		t.Errorf("Expected return value of 4, got %v", v)
+	}
+
+	// 10 + 20 + 40 msecs
+	if dur < 70*time.Millisecond {
+		t.Errorf("Expected at least 70 msecs of backoff, took %v", dur)
+	}
+}
+
+var exponentialTests = []struct {
+	n   int
+	out time.Duration
+}{
+	{0, 0},
+	{1, 10},
+	{2, 20},
+	{3, 40},
+	{4, 80},
+	{5, 100},
+	{100, 100},
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{Base: 10, Max: 100}
+	for i, tt := range exponentialTests {
+		if d := b.Delay(tt.n); d != tt.out {
+			t.Errorf("%d. Delay(%d) => %v, want %v", i, tt.n, d, tt.out)
+		}
+	}
+
+	u := ExponentialBackoff{Base: time.Second}
+	if d := u.Delay(200); d <= 0 {
+		t.Errorf("Uncapped delay should saturate, not overflow, got %v", d)
+	}
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	b := JitteredBackoff{Base: 10, Max: 100}
+	for i, tt := range exponentialTests {
+		for j := 0; j < 100; j++ {
+			if d := b.Delay(tt.n); d < 0 || d > tt.out {
+				t.Errorf("%d. Delay(%d) => %v, want value in [0,%v]", i, tt.n, d, tt.out)
+			}
+		}
+	}
+}
@@ -5,8 +5,10 @@
 package stm
 
 import (
+	"errors"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -101,35 +103,27 @@ func TestNoCommitOnAbort(t *testing.T) {
 		return ret
 	}
 
-	defer func() {
-
-		r := recover()
-
-		if r == nil {
-			t.Errorf("Expected a panic due to transaction abort, didn't get one")
-		} else if r.(error).Error() != "Transaction aborted" {
-			t.Errorf("Expected transaction abort error, got %v", r)
-
-		}
-
-		if v := r1.Deref(nil); v != init1 {
-			t.Errorf("Expected r1 to have value %v, got %v", init1, v)
-		}
+	v, e := RunInTransaction(f)
 
-		if save1 != init1 {
-			t.Errorf("Expected save1 to have value %v, got %v", init1, save1)
-		}
+	if !errors.Is(e, ErrAborted) {
+		t.Errorf("Expected transaction abort error, got %v", e)
+	}
 
-		if save2 != new1 {
-			t.Errorf("Expected save2 to have value %v, got %v", new1, save2)
-		}
-	}()
+	if v != nil {
+		t.Errorf("Expected nil return value from aborted transaction, got %v", v)
+	}
 
-	RunInTransaction(f)
+	if v := r1.Deref(nil); v != init1 {
+		t.Errorf("Expected r1 to have value %v, got %v", init1, v)
+	}
 
-	// shouldn't get here
-	t.Errorf("Abort should prevented us from reaching this point")
+	if save1 != init1 {
+		t.Errorf("Expected save1 to have value %v, got %v", init1, save1)
+	}
 
+	if save2 != new1 {
+		t.Errorf("Expected save2 to have value %v, got %v", new1, save2)
+	}
 }
 
 func TestNoSetAfterCommute(t *testing.T) {
@@ -157,32 +151,28 @@ func TestNoSetAfterCommute(t *testing.T) {
 		return ret
 	}
 
-	defer func() {
-		r := recover()
-		if r == nil {
-			t.Errorf("Expected a panic due to transaction abort, didn't get one")
-		} else if r.(error).Error() != "Can't set after commute" {
-			t.Errorf("Expected 'can't set' error, got %v", r)
+	_, e := RunInTransaction(f)
 
-		}
-
-		if v := r1.Deref(nil); v != init1 {
-			t.Errorf("Expected r1 to have value %v, got %v", init1, v)
-		}
-
-		if !point1 {
-			t.Errorf("Expected to do the commute, but failed earlier")
-		}
+	if !errors.Is(e, ErrSetAfterCommute) {
+		t.Errorf("Expected 'can't set' error, got %v", e)
+	}
 
-		if point2 {
-			t.Errorf("Expected to panic during the set, but made it through")
-		}
+	var sac *SetAfterCommuteError
+	if !errors.As(e, &sac) || sac.Ref != r1 {
+		t.Errorf("Expected a *SetAfterCommuteError for r1, got %v", e)
+	}
 
-	}()
+	if v := r1.Deref(nil); v != init1 {
+		t.Errorf("Expected r1 to have value %v, got %v", init1, v)
+	}
 
-	RunInTransaction(f)
+	if !point1 {
+		t.Errorf("Expected to do the commute, but failed earlier")
+	}
 
-	t.Errorf("Should not have reached this point, should have panicked")
+	if point2 {
+		t.Errorf("Expected to fail during the set, but made it through")
+	}
 }
 
 func TestSimpleCommute(t *testing.T) {
@@ -303,3 +293,179 @@ func TestSimpleInterference(t *testing.T) {
 	t.Errorf("%v %v %v %v %v", ngEnter, ngExit, r1.Deref(nil), nfEnter, nfExit)
 
 }
+
+// Every transaction blocked on a conflicting transaction is released when it stops.
+func TestBlockedTransactionsAllReleased(t *testing.T) {
+	holder := newTxInfo(txRunning, 1)
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { results[i] = recover() }()
+			tx := NewTxWithOptions(TxOptions{LockWait: 10 * time.Second})
+			tx.info = newTxInfo(txRunning, 2)
+			tx.blockAndBail(holder, RetryBargeLost, nil)
+		}(i)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	t0 := time.Now()
+	holder.setStatus(txRetry, true)
+	wg.Wait()
+
+	for i, r := range results {
+		if _, ok := r.(*retrySignal); !ok {
+			t.Errorf("Expected waiter %d to bail with a retry, got %v", i, r)
+		}
+	}
+	if d := time.Since(t0); d > 5*time.Second {
+		t.Errorf("Expected waiters to be released when the holder stopped, took %v", d)
+	}
+}
+
+// A barged transaction releases its waiters, and stopping it afterwards is harmless.
+func TestStopAfterBarge(t *testing.T) {
+	victim := newTxInfo(txRunning, 10)
+
+	tx := NewTx()
+	tx.startPoint = 1
+	tx.startTime = time.Now().Add(-time.Second)
+	if !tx.barge(victim) {
+		t.Fatalf("Expected older transaction to barge")
+	}
+
+	waiter := NewTxWithOptions(TxOptions{LockWait: 10 * time.Second})
+	waiter.info = newTxInfo(txRunning, 11)
+	t0 := time.Now()
+	func() {
+		defer func() { recover() }()
+		waiter.blockAndBail(victim, RetryBargeLost, nil)
+	}()
+	if d := time.Since(t0); d > 5*time.Second {
+		t.Errorf("Expected barge to release the victim's waiters, took %v", d)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Expected stopping a barged transaction to succeed, got panic %v", r)
+		}
+	}()
+	victim.setStatus(txRetry, true)
+}
+
+// Locks taken by an attempt that retries are released before the next attempt.
+func TestRetryReleasesLocks(t *testing.T) {
+	a := NewRef(0)
+	b := NewRef(0)
+
+	incr := func(v interface{}, args ...interface{}) interface{} {
+		return v.(int) + 1
+	}
+
+	attempts := 0
+	f := func(tx *Tx) interface{} {
+		attempts++
+		if attempts == 2 {
+			b.exitReadLock()
+		}
+		a.Commute(tx, incr)
+		b.Set(tx, attempts)
+		if attempts == 1 {
+			// b can't be write-locked at commit, after a has been: retry
+			b.enterReadLock()
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunInTransactionWithOptions(TxOptions{LockWait: 10 * time.Millisecond}, f)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Transaction blocked on a lock held by its own earlier attempt")
+	}
+
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %v", attempts)
+	}
+	if v := a.Deref(nil); v != 1 {
+		t.Errorf("Expected a to have value 1, got %v", v)
+	}
+	if v := b.Deref(nil); v != 2 {
+		t.Errorf("Expected b to have value 2, got %v", v)
+	}
+}
+
+// A transaction killed after its body has run retries rather than reporting success without committing.
+func TestKilledTransactionRetries(t *testing.T) {
+	r := NewRef(0)
+
+	attempts := 0
+	f := func(tx *Tx) interface{} {
+		attempts++
+		r.Set(tx, attempts)
+		if attempts == 1 {
+			// as if barged by an older transaction
+			atomic.StoreUint32(&tx.info.status, txKilled)
+		}
+		return attempts
+	}
+
+	v, err := RunInTransaction(f)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attempts != 2 || v != 2 {
+		t.Errorf("Expected a second attempt to commit, got %v attempts returning %v", attempts, v)
+	}
+	if v := r.Deref(nil); v != 2 {
+		t.Errorf("Expected r to have value 2, got %v", v)
+	}
+}
+
+type writeRecorder struct {
+	NopObserver
+	writes []RefWrite
+}
+
+func (w *writeRecorder) OnCommit(tx *Tx, point uint64, writes []RefWrite) {
+	w.writes = writes
+}
+
+// Refs are locked and committed in order of creation, whatever order the transaction touched them in.
+func TestCommitOrder(t *testing.T) {
+	refs := make([]*Ref, 20)
+	for i := range refs {
+		refs[i] = NewRef(0)
+	}
+
+	rec := &writeRecorder{}
+	_, err := RunInTransactionWithOptions(TxOptions{Observer: rec}, func(tx *Tx) interface{} {
+		for i := len(refs) - 1; i >= 0; i-- {
+			refs[i].Set(tx, i)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(rec.writes) != len(refs) {
+		t.Fatalf("Expected %v writes, got %v", len(refs), len(rec.writes))
+	}
+	for i, w := range rec.writes {
+		if w.Ref != refs[i] {
+			t.Errorf("Expected write %v to be to ref %v, got ref %v", i, refs[i].id, w.Ref.id)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+// Typed wrappers for Ref and Atom.
+// The wrappers hold an ordinary *Ref or *Atom, so typed and untyped Refs
+// can be used together in the same transaction.
+
+// A TypedRef is a Ref whose values are of type T.
+type TypedRef[T any] struct {
+	ref *Ref
+}
+
+// NewTypedRef returns a TypedRef with the given initial value.
+func NewTypedRef[T any](val T) *TypedRef[T] {
+	return &TypedRef[T]{ref: NewRef(val)}
+}
+
+// WrapRef returns a TypedRef sharing the given Ref.
+// All values of the Ref must be of type T (or nil, read as the zero value of T).
+func WrapRef[T any](r *Ref) *TypedRef[T] {
+	return &TypedRef[T]{ref: r}
+}
+
+// Ref returns the underlying untyped Ref.
+func (r *TypedRef[T]) Ref() *Ref {
+	return r.ref
+}
+
+// Deref gets the value in the transaction (or the current value, if tx is nil).
+func (r *TypedRef[T]) Deref(tx *Tx) T {
+	return asType[T](r.ref.Deref(tx))
+}
+
+// Set sets the value in the transaction.  Returns the value.
+func (r *TypedRef[T]) Set(tx *Tx, val T) T {
+	r.ref.Set(tx, val)
+	return val
+}
+
+// Alter sets the value in the transaction to fn(current value).  Returns the new value.
+func (r *TypedRef[T]) Alter(tx *Tx, fn func(T) T) T {
+	return asType[T](r.ref.Alter(tx, typedCFn(fn)))
+}
+
+// Commute applies fn to the value in the transaction, and again at commit time
+// to the latest committed value.  Returns the in-transaction value.
+func (r *TypedRef[T]) Commute(tx *Tx, fn func(T) T) T {
+	return asType[T](r.ref.Commute(tx, typedCFn(fn)))
+}
+
+// Touch ensures the value is not changed by other transactions before this one commits.
+func (r *TypedRef[T]) Touch(tx *Tx) {
+	r.ref.Touch(tx)
+}
+
+// A TypedAtom is an Atom whose values are of type T.
+type TypedAtom[T any] struct {
+	atom *Atom
+}
+
+// NewTypedAtom returns a TypedAtom with the given initial value.
+func NewTypedAtom[T any](val T) *TypedAtom[T] {
+	return &TypedAtom[T]{atom: NewAtom(val)}
+}
+
+// WrapAtom returns a TypedAtom sharing the given Atom.
+// All values of the Atom must be of type T (or nil, read as the zero value of T).
+func WrapAtom[T any](a *Atom) *TypedAtom[T] {
+	return &TypedAtom[T]{atom: a}
+}
+
+// Atom returns the underlying untyped Atom.
+func (a *TypedAtom[T]) Atom() *Atom {
+	return a.atom
+}
+
+// Deref returns the current value.
+func (a *TypedAtom[T]) Deref() T {
+	return asType[T](a.atom.Deref())
+}
+
+// Reset sets the value.  Returns the new value.
+func (a *TypedAtom[T]) Reset(val T) T {
+	a.atom.Reset(val)
+	return val
+}
+
+// Swap sets the value to fn(current value).  Returns the new value.
+func (a *TypedAtom[T]) Swap(fn func(T) T) T {
+	return asType[T](a.atom.Swap(typedCFn(fn)))
+}
+
+// CompareAndSet sets the value to newVal if the current value is == oldVal.
+func (a *TypedAtom[T]) CompareAndSet(oldVal T, newVal T) bool {
+	return a.atom.CompareAndSet(oldVal, newVal)
+}
+
+// Convert an untyped value, treating nil as the zero value
+func asType[T any](v interface{}) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return v.(T)
+}
+
+func typedCFn[T any](fn func(T) T) CFn {
+	return func(old interface{}, args ...interface{}) interface{} {
+		return fn(asType[T](old))
+	}
+}
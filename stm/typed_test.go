@@ -0,0 +1,75 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"testing"
+)
+
+func TestTypedRefInTransaction(t *testing.T) {
+	count := NewTypedRef(10)
+	name := NewTypedRef("x")
+	plain := NewRef(100)
+
+	double := func(n int) int { return 2 * n }
+
+	v, e := RunInTransaction(func(tx *Tx) interface{} {
+		n := count.Alter(tx, double)
+		name.Set(tx, name.Deref(tx)+"y")
+		plain.Set(tx, plain.Deref(tx).(int)+n)
+		return count.Commute(tx, func(n int) int { return n + 1 })
+	})
+
+	if e != nil {
+		t.Errorf("Expected no error, got %v", e)
+	}
+
+	if v != 21 {
+		t.Errorf("Expected 21, got %v", v)
+	}
+
+	if c := count.Deref(nil); c != 21 {
+		t.Errorf("Expected count to be 21, got %d", c)
+	}
+
+	if s := name.Deref(nil); s != "xy" {
+		t.Errorf("Expected name to be xy, got %q", s)
+	}
+
+	if p := plain.Deref(nil); p != 120 {
+		t.Errorf("Expected plain to be 120, got %v", p)
+	}
+}
+
+func TestWrapRefNilIsZero(t *testing.T) {
+	r := WrapRef[int](NewRef(nil))
+
+	if v := r.Deref(nil); v != 0 {
+		t.Errorf("Expected nil to read as 0, got %d", v)
+	}
+
+	if r.Ref() == nil {
+		t.Errorf("Expected the underlying Ref")
+	}
+}
+
+func TestTypedAtom(t *testing.T) {
+	a := NewTypedAtom([]string{"a"})
+
+	a.Swap(func(s []string) []string { return append(s, "b") })
+
+	if v := a.Deref(); len(v) != 2 || v[1] != "b" {
+		t.Errorf("Expected [a b], got %v", v)
+	}
+
+	n := NewTypedAtom(5)
+	if !n.CompareAndSet(5, 6) || n.Deref() != 6 {
+		t.Errorf("Expected CompareAndSet to change 5 to 6, got %d", n.Deref())
+	}
+
+	if n.Reset(7) != 7 || n.Atom().Deref() != 7 {
+		t.Errorf("Expected Reset to set 7")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Vars
+//
+// A Clojure Var has a root value shared by all threads, which a thread can rebind
+// for the dynamic extent of a binding form.  The bindings live in a thread-local stack of frames.
+// Go has no goroutine-local storage, so here the frames are carried by a context.Context:
+// WithBindings runs a function with a context carrying the new frame,
+// and Deref(ctx) resolves the innermost binding in ctx, falling back to the root.
+//
+// Bindings reach whatever the context reaches.  A Future started with NewFutureContext
+// sees the bindings of its context; ConveyBindings copies bindings onto an unrelated context,
+// such as one for work that should outlive the caller's cancellation.
+
+// ErrVarNotBound indicates a Set on a Var with no binding in the context.
+var ErrVarNotBound = errors.New("Can't set a Var without a binding")
+
+// A Var is a named reference with a root value and per-context bindings.
+type Var struct {
+	name string
+	root atomic.Pointer[atomBox]
+
+	// Serializes AlterRoot
+	lock sync.Mutex
+}
+
+// NewVar returns a Var with the given name and root value.
+func NewVar(name string, root interface{}) *Var {
+	v := &Var{name: name}
+	v.root.Store(&atomBox{root})
+	return v
+}
+
+func (v *Var) String() string {
+	return fmt.Sprintf("#'%s", v.name)
+}
+
+// Name returns the name of the Var.
+func (v *Var) Name() string {
+	return v.name
+}
+
+// Root returns the root value.
+func (v *Var) Root() interface{} {
+	return v.root.Load().val
+}
+
+// Value returns the root value.  (Use Deref to see bindings.)
+func (v *Var) Value() interface{} {
+	return v.Root()
+}
+
+// BindRoot sets the root value.
+func (v *Var) BindRoot(val interface{}) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.root.Store(&atomBox{val})
+}
+
+// AlterRoot sets the root value to fn(root value, args...) and returns the new value.
+// Calls are serialized, so fn sees the result of the previous call.
+func (v *Var) AlterRoot(fn CFn, args ...interface{}) interface{} {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	newVal := fn(v.root.Load().val, args...)
+	v.root.Store(&atomBox{newVal})
+	return newVal
+}
+
+// A frame holds every binding in effect, innermost first, so lookup needs one map access.
+// Each binding is boxed so that Set in an inner function is seen by later Derefs in the same frame.
+type frame struct {
+	bindings map[*Var]*atomic.Pointer[atomBox]
+}
+
+type frameKey struct{}
+
+func frameFrom(ctx context.Context) *frame {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(frameKey{}).(*frame)
+	return f
+}
+
+// BindingContext returns a copy of ctx in which the Vars have the given values,
+// in addition to the bindings already in ctx.
+func BindingContext(ctx context.Context, bindings map[*Var]interface{}) context.Context {
+	outer := frameFrom(ctx)
+	f := &frame{bindings: make(map[*Var]*atomic.Pointer[atomBox])}
+	if outer != nil {
+		for v, b := range outer.bindings {
+			f.bindings[v] = b
+		}
+	}
+	for v, val := range bindings {
+		b := new(atomic.Pointer[atomBox])
+		b.Store(&atomBox{val})
+		f.bindings[v] = b
+	}
+	return context.WithValue(ctx, frameKey{}, f)
+}
+
+// WithBindings calls fn with a context in which the Vars have the given values.
+// Returns the value returned by fn.  (This is Clojure's binding macro.)
+func WithBindings(ctx context.Context, bindings map[*Var]interface{}, fn func(ctx context.Context) interface{}) interface{} {
+	return fn(BindingContext(ctx, bindings))
+}
+
+// ConveyBindings returns a copy of dst carrying the Var bindings of src.
+// Bindings already in dst are replaced.
+func ConveyBindings(dst, src context.Context) context.Context {
+	f := frameFrom(src)
+	if f == nil {
+		return dst
+	}
+	return context.WithValue(dst, frameKey{}, f)
+}
+
+// Deref returns the innermost value bound to the Var in ctx, or the root value if it is not bound.
+// ctx may be nil.
+func (v *Var) Deref(ctx context.Context) interface{} {
+	if f := frameFrom(ctx); f != nil {
+		if b, ok := f.bindings[v]; ok {
+			return b.Load().val
+		}
+	}
+	return v.Root()
+}
+
+// IsBound returns true if the Var has a binding in ctx.
+func (v *Var) IsBound(ctx context.Context) bool {
+	f := frameFrom(ctx)
+	if f == nil {
+		return false
+	}
+	_, ok := f.bindings[v]
+	return ok
+}
+
+// Set changes the innermost binding of the Var in ctx.
+// The change is seen by every context sharing that binding.
+// Returns ErrVarNotBound if the Var is not bound in ctx; the root is never changed by Set.
+func (v *Var) Set(ctx context.Context, val interface{}) error {
+	f := frameFrom(ctx)
+	if f == nil {
+		return ErrVarNotBound
+	}
+	b, ok := f.bindings[v]
+	if !ok {
+		return ErrVarNotBound
+	}
+	b.Store(&atomBox{val})
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2014 David Miller. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVarRoot(t *testing.T) {
+	v := NewVar("x", 1)
+	if v.Deref(nil) != 1 || v.Value() != 1 {
+		t.Errorf("Deref(nil), Value() => %v, %v; want 1, 1", v.Deref(nil), v.Value())
+	}
+	if r := v.AlterRoot(func(x interface{}, args ...interface{}) interface{} { return x.(int) + args[0].(int) }, 10); r != 11 {
+		t.Errorf("AlterRoot => %v, want 11", r)
+	}
+	v.BindRoot(20)
+	if v.Root() != 20 {
+		t.Errorf("Root() after BindRoot => %v, want 20", v.Root())
+	}
+	if s := v.String(); s != "#'x" {
+		t.Errorf("String() => %q, want #'x", s)
+	}
+}
+
+func TestVarBindings(t *testing.T) {
+	x := NewVar("x", "root-x")
+	y := NewVar("y", "root-y")
+	ctx := context.Background()
+
+	WithBindings(ctx, map[*Var]interface{}{x: "outer-x", y: "outer-y"}, func(ctx context.Context) interface{} {
+		if x.Deref(ctx) != "outer-x" || y.Deref(ctx) != "outer-y" {
+			t.Errorf("outer bindings: %v, %v", x.Deref(ctx), y.Deref(ctx))
+		}
+		WithBindings(ctx, map[*Var]interface{}{x: "inner-x"}, func(ctx context.Context) interface{} {
+			if x.Deref(ctx) != "inner-x" || y.Deref(ctx) != "outer-y" {
+				t.Errorf("inner bindings: %v, %v", x.Deref(ctx), y.Deref(ctx))
+			}
+			return nil
+		})
+		if x.Deref(ctx) != "outer-x" {
+			t.Errorf("outer binding after inner returned: %v", x.Deref(ctx))
+		}
+		return nil
+	})
+	if x.Deref(ctx) != "root-x" || x.IsBound(ctx) {
+		t.Errorf("x outside bindings: %v, bound %v", x.Deref(ctx), x.IsBound(ctx))
+	}
+}
+
+func TestVarSet(t *testing.T) {
+	x := NewVar("x", 0)
+	ctx := context.Background()
+	if err := x.Set(ctx, 1); err != ErrVarNotBound {
+		t.Errorf("Set without binding => %v, want ErrVarNotBound", err)
+	}
+	WithBindings(ctx, map[*Var]interface{}{x: 1}, func(ctx context.Context) interface{} {
+		if err := x.Set(ctx, 2); err != nil {
+			t.Errorf("Set => %v", err)
+		}
+		if x.Deref(ctx) != 2 {
+			t.Errorf("Deref after Set => %v, want 2", x.Deref(ctx))
+		}
+		return nil
+	})
+	if x.Root() != 0 {
+		t.Errorf("Set changed the root to %v", x.Root())
+	}
+}
+
+func TestVarConveyance(t *testing.T) {
+	x := NewVar("x", 0)
+	ctx := BindingContext(context.Background(), map[*Var]interface{}{x: 42})
+
+	f := NewFutureContext(ctx, func(ctx context.Context) (interface{}, error) {
+		return x.Deref(ctx), nil
+	})
+	if v := f.Value(); v != 42 {
+		t.Errorf("Future saw %v, want 42", v)
+	}
+
+	detached := ConveyBindings(context.Background(), ctx)
+	if v := x.Deref(detached); v != 42 {
+		t.Errorf("conveyed binding => %v, want 42", v)
+	}
+	if v := x.Deref(ConveyBindings(context.Background(), context.Background())); v != 0 {
+		t.Errorf("conveying no bindings => %v, want 0", v)
+	}
+}